@@ -2,12 +2,18 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	cli "github.com/virtual-kubelet/node-cli"
 	logruscli "github.com/virtual-kubelet/node-cli/logrus"
 	"github.com/virtual-kubelet/node-cli/opts"
+	"github.com/virtual-kubelet/node-cli/provider"
 	"github.com/virtual-kubelet/virtual-kubelet/log"
 	logruslogger "github.com/virtual-kubelet/virtual-kubelet/log/logrus"
 
@@ -22,6 +28,13 @@ var (
 	numberOfWorkers = 50
 )
 
+// The kubelet pod API's TLS certificate and key are read from these
+// environment variables, as they would be by node-cli.
+const (
+	envCertPath = "APISERVER_CERT_LOCATION"
+	envKeyPath  = "APISERVER_KEY_LOCATION"
+)
+
 func main() {
 	ctx := cli.ContextWithCancelOnSignal(context.Background())
 
@@ -37,10 +50,29 @@ func main() {
 	o.Version = strings.Join([]string{k8sVersion, name, buildVersion}, "-")
 	o.PodSyncWorkers = numberOfWorkers
 
+	// NOTE(negz): node-cli serves only part of the kubelet pod API; it has no
+	// way to route attach or port forward requests to our provider. We serve
+	// the pod API ourselves, so we hide its TLS certificate from node-cli to
+	// stop it from serving the pod API on the same port.
+	certPath, keyPath := os.Getenv(envCertPath), os.Getenv(envKeyPath)
+	_ = os.Unsetenv(envCertPath)
+	_ = os.Unsetenv(envKeyPath)
+
 	node, err := cli.New(ctx,
 		cli.WithBaseOpts(o),
 		cli.WithCLIVersion(buildVersion, buildTime),
-		cli.WithProvider(name, kubernetes.NewProvider),
+		cli.WithProvider(name, func(ic provider.InitConfig) (provider.Provider, error) {
+			p, err := kubernetes.NewProvider(ctx, ic)
+			if err != nil {
+				return nil, err
+			}
+			if certPath == "" || keyPath == "" {
+				log.G(ctx).Error("TLS certificates not provided, not serving pod API")
+				return p, nil
+			}
+			t := kubernetes.StreamTimeouts{Idle: o.StreamIdleTimeout, Creation: o.StreamCreationTimeout}
+			return p, servePods(ctx, kubernetes.NewPodHandler(p, t), fmt.Sprintf(":%d", ic.DaemonPort), certPath, keyPath)
+		}),
 		cli.WithPersistentFlags(logConfig.FlagSet()),
 		cli.WithPersistentPreRunCallback(func() error {
 			return logruscli.Configure(logConfig, logger)
@@ -55,3 +87,28 @@ func main() {
 		log.G(ctx).Fatal(err)
 	}
 }
+
+// servePods serves the supplied kubelet pod API handler at the supplied address
+// until the supplied context is done.
+func servePods(ctx context.Context, h http.Handler, addr, certPath, keyPath string) error {
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return errors.Wrap(err, "cannot load pod API TLS certificate")
+	}
+	l, err := tls.Listen("tcp", addr, &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+	if err != nil {
+		return errors.Wrapf(err, "cannot listen for pod API requests at %s", addr)
+	}
+
+	s := &http.Server{Handler: h}
+	go func() {
+		<-ctx.Done()
+		_ = s.Close()
+	}()
+	go func() {
+		if err := s.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.G(ctx).WithError(err).Error("cannot serve pod API")
+		}
+	}()
+	return nil
+}
@@ -5,14 +5,21 @@ go 1.13
 require (
 	github.com/BurntSushi/toml v0.3.1
 	github.com/crossplane/crossplane-runtime v0.9.0
+	github.com/evanphx/json-patch v4.5.0+incompatible
 	github.com/google/go-cmp v0.5.2
 	github.com/pkg/errors v0.8.1
+	github.com/prometheus/client_golang v1.1.0
 	github.com/sirupsen/logrus v1.4.2
 	github.com/virtual-kubelet/node-cli v0.3.1
 	github.com/virtual-kubelet/virtual-kubelet v1.3.0
+	go.opentelemetry.io/otel v0.13.0
+	go.opentelemetry.io/otel/exporters/otlp v0.13.0
+	go.opentelemetry.io/otel/sdk v0.13.0
+	google.golang.org/grpc v1.32.0
 	k8s.io/api v0.18.6
 	k8s.io/apimachinery v0.18.6
 	k8s.io/client-go v0.18.6
+	k8s.io/kubernetes v1.18.4
 	sigs.k8s.io/controller-runtime v0.6.2
 )
 
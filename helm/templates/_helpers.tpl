@@ -46,6 +46,43 @@ resync_period = "10m"
 kubeconfig_path = "/etc/vk-config/remote.yaml"
 resync_period = "10m"
 
+# Pods may instead be spread across several remote clusters by replacing the
+# [remote] section above with [[remotes]] sections, for example:
+#
+# [[remotes]]
+# name = "east"
+# kubeconfig_path = "/etc/vk-config/east.yaml"
+#
+# [[remotes]]
+# name = "west"
+# kubeconfig_path = "/etc/vk-config/west.yaml"
+#
+# [placement]
+# # One of "round-robin", "least-loaded", or "selected". The selected policy
+# # honours the remote cluster named by each pod's actual.vk/remote label or
+# # annotation.
+# policy = "round-robin"
+
+[namespaces]
+# One of "hash", "readable", or "template". The template strategy uses the Go
+# template in naming_template, for example "{{`{{ .NodeName }}-{{ .Namespace }}`}}".
+naming_strategy = "hash"
+
+# Uncomment to apply a ResourceQuota and LimitRange to every remote namespace.
+# Overrides replace the default quota for particular local namespaces; an empty
+# override exempts a namespace from quota.
+#
+# [namespaces.quota.resource_quota]
+# "requests.cpu" = "10"
+# "requests.memory" = "20Gi"
+#
+# [namespaces.quota.limit_range.default]
+# cpu = "500m"
+# memory = "512Mi"
+#
+# [namespaces.overrides.batch.resource_quota]
+# "requests.cpu" = "50"
+
 [pods]
 env = [
     # Inject this environment variable into all remote pods. In this case we're
@@ -55,6 +92,98 @@ env = [
     { name = "KUBERNETES_SERVICE_HOST", value = "{{ required "A local API-server host is required" .Values.local.apiserverHost }}"}
 ]
 
+# Remote pod IPs are usually not routable from the local cluster. Pods may
+# instead be exposed by a remote "LoadBalancer" service, whose address is
+# reported as their pod IP. Pods with the same value for the group_by label
+# share a service, for example:
+#
+# [pods.expose]
+# type = "LoadBalancer"
+# group_by = "app"
+
+# By default node selectors, affinity, and topology spread constraints are
+# removed from remote pods. In "translate" mode pod (anti-)affinity is kept, and
+# node constraints are kept for the listed node label keys, for example:
+#
+# [pods.scheduling]
+# mode = "translate"
+# node_label_keys = ["topology.kubernetes.io/zone", "topology.kubernetes.io/region"]
+
+# Priority and runtime classes are cluster scoped and may not exist remotely.
+# They may be replicated (prefixed with the node name) or mapped to existing
+# remote classes. System priority classes are never replicated. For example:
+#
+# [pods.priority_classes]
+# mode = "replicate"
+#
+# [pods.runtime_classes]
+# mode = "map"
+# map = { gvisor = "remote-gvisor" }
+
+# Mutations are applied to the remote pods corresponding to the local pods they
+# select, by namespace and/or label selector. They may add tolerations, node
+# selectors, labels, and annotations, set priority and runtime class names, and
+# apply RFC 6902 JSON patches, for example to steer pods onto a remote node pool:
+#
+# [[pods.mutations]]
+# namespaces = ["batch"]
+# selector = "tier=worker"
+# node_selector = { pool = "ak-batch" }
+# tolerations = [{ key = "pool", value = "ak-batch", effect = "NoSchedule" }]
+# patch = '[{"op": "add", "path": "/spec/hostname", "value": "worker"}]'
+
+# Persistent volume claims are replicated to the remote cluster. Their storage
+# classes may be mapped to remote storage classes, for example:
+#
+# [storage.classes]
+# standard = "remote-standard"
+
+# Local services may be mirrored to remote namespaces, allowing remote pods to
+# reach them by name. Mode "endpoints" mirrors each service's endpoints, and
+# requires remote pods to be able to reach local pod IPs. Mode "external-name"
+# resolves each service to <service>.<namespace>.svc.<domain>, for example:
+#
+# [services]
+# mirror = "external-name"
+# domain = "local.example.org"
+
+[events]
+# Mirror events recorded on remote pods (e.g. image pull errors) to local pods.
+# Mirroring is rate limited per remote cluster.
+mirror = true
+qps = 1
+burst = 25
+
+[metrics]
+# Serve Prometheus metrics at /metrics on this port.
+port = {{ .Values.metrics.port }}
+
+# Uncomment to export OpenTelemetry traces of provider operations and remote API
+# calls to an OTLP gRPC collector.
+#
+# [tracing]
+# endpoint = "otel-collector.monitoring:55680"
+# insecure = true
+
+[gc]
+# Periodically delete remote namespaces, config maps, and secrets that are no
+# longer needed by any pod scheduled to this node.
+interval = "10m"
+grace_period = "5m"
+dry_run = false
+
+[node.resources]
+# Set mode to "remote" to derive the node's resources from the remote cluster's
+# ready nodes, rather than using the static allocatable resources below.
+mode = "static"
+refresh_interval = "1m"
+
+[node.health]
+# The node reports that it is not ready after this many consecutive failed
+# probes of the remote API server.
+probe_interval = "10s"
+failure_threshold = 3
+
 [node.resources.allocatable]
 cpu = "100"
 storage = "1024G"
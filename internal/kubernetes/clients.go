@@ -1,13 +1,20 @@
 package kubernetes
 
 import (
+	"context"
+
 	"github.com/pkg/errors"
 	"github.com/virtual-kubelet/virtual-kubelet/log"
 	corev1 "k8s.io/api/core/v1"
+	nodev1beta1 "k8s.io/api/node/v1beta1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
@@ -24,8 +31,9 @@ type Client struct {
 	Config *rest.Config
 }
 
-// NewClient returns a client for a Kubernetes cluster.
-func NewClient(cc ClientConfig) (Client, error) {
+// NewClient returns a client for a Kubernetes cluster. Calls made by the client
+// are traced, and attributed to the supplied cluster name.
+func NewClient(name string, cc ClientConfig) (Client, error) {
 	var cfg *rest.Config
 	var err error
 	if cc.KubeConfigPath != "" {
@@ -45,6 +53,12 @@ func NewClient(cc ClientConfig) (Client, error) {
 	if err := corev1.AddToScheme(s); err != nil {
 		return Client{}, errors.Wrap(err, "cannot register core API types with Kubernetes client")
 	}
+	if err := schedulingv1.AddToScheme(s); err != nil {
+		return Client{}, errors.Wrap(err, "cannot register scheduling API types with Kubernetes client")
+	}
+	if err := nodev1beta1.AddToScheme(s); err != nil {
+		return Client{}, errors.Wrap(err, "cannot register node API types with Kubernetes client")
+	}
 
 	ca, err := cache.New(cfg, cache.Options{Scheme: s, Resync: &cc.ResyncInterval})
 	if err != nil {
@@ -65,23 +79,41 @@ func NewClient(cc ClientConfig) (Client, error) {
 	if err != nil {
 		return Client{}, errors.Wrap(err, "cannot create Kubernetes client")
 	}
+	tcl := NewTracingClient(cl, name)
 
-	cs, err := kubernetes.NewForConfig(cfg)
+	// The clientset is used for calls the controller-runtime client can't make,
+	// e.g. to stream logs or execute commands, so we trace it at the transport.
+	tcfg := rest.CopyConfig(cfg)
+	tcfg.Wrap(NewTracingTransport(name))
+	cs, err := kubernetes.NewForConfig(tcfg)
 	if err != nil {
 		return Client{}, errors.Wrap(err, "cannot create Kubernetes clientset")
 	}
 
 	return Client{
 		ClientApplicator: resource.ClientApplicator{
-			Client: &client.DelegatingClient{
+			Client: NewTracingClient(&client.DelegatingClient{
 				Reader:       &client.DelegatingReader{CacheReader: ca, ClientReader: cl},
 				Writer:       cl,
 				StatusClient: cl,
-			},
-			Applicator: resource.NewAPIUpdatingApplicator(cl),
+			}, name),
+			Applicator: resource.NewAPIUpdatingApplicator(tcl),
 		},
 		Informers: ca,
 		Interface: cs,
-		Config:    cfg,
+		Config:    tcfg,
 	}, nil
 }
+
+// NewEventRecorder returns an EventRecorder that records events in the API
+// server of the supplied client. Events are attributed to the supplied node.
+// Events are no longer recorded once the supplied context is done.
+func NewEventRecorder(ctx context.Context, c Client, nodeName string) record.EventRecorder {
+	b := record.NewBroadcaster()
+	b.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: c.CoreV1().Events("")})
+	go func() {
+		<-ctx.Done()
+		b.Shutdown()
+	}()
+	return b.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "actual-kubelets", Host: nodeName})
+}
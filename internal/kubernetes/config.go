@@ -10,6 +10,8 @@ import (
 	"github.com/virtual-kubelet/node-cli/provider"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/negz/actual-kubelets/internal/remote"
 )
 
 // A Config contains the configuration that a provider needs - both that
@@ -19,6 +21,22 @@ type Config struct {
 	ConfigFile
 }
 
+// A Duration is a time.Duration that may be parsed from a string such as "10m"
+// or "30s" when it appears in a TOML config file.
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText unmarshals a Duration from the supplied text.
+func (d *Duration) UnmarshalText(text []byte) error {
+	dd, err := time.ParseDuration(string(text))
+	if err != nil {
+		return errors.Wrapf(err, "cannot parse duration %q", string(text))
+	}
+	d.Duration = dd
+	return nil
+}
+
 // A ClientConfig is used to configure a Kubernetes client.
 type ClientConfig struct {
 	// KubeConfigPath is an optional path to a kubeconfig file that will be used
@@ -32,11 +50,371 @@ type ClientConfig struct {
 	ResyncInterval time.Duration `toml:"resync_interval"`
 }
 
+// DefaultRemoteName is the name of the remote configured by the remote section
+// of the config file.
+const DefaultRemoteName = "default"
+
+// A RemoteConfig is used to configure one of several remote API servers.
+type RemoteConfig struct {
+	// Name of the remote API server. Must be unique among all remotes.
+	Name string `toml:"name"`
+
+	// ClientConfig configures the client used to connect to this remote.
+	ClientConfig
+}
+
+// A PlacementPolicy determines which remote API server a pod is created in.
+type PlacementPolicy string
+
+// Placement policies.
+const (
+	// PlacementPolicyRoundRobin creates each pod in the next remote API
+	// server, in the order they are configured.
+	PlacementPolicyRoundRobin PlacementPolicy = "round-robin"
+
+	// PlacementPolicyLeastLoaded creates each pod in the remote API server
+	// that is running the fewest pods on behalf of this node.
+	PlacementPolicyLeastLoaded PlacementPolicy = "least-loaded"
+
+	// PlacementPolicySelected creates each pod in the remote API server named
+	// by one of its labels or annotations. Pods without the label or
+	// annotation are placed in round-robin order.
+	PlacementPolicySelected PlacementPolicy = "selected"
+)
+
+// The PlacementConfig is used to configure which remote API server each pod is
+// created in.
+type PlacementConfig struct {
+	// Policy used to place pods. Defaults to round-robin.
+	Policy PlacementPolicy `toml:"policy"`
+
+	// Key of the label or annotation that names a pod's remote API server
+	// when using the selected policy. Defaults to actual.vk/remote.
+	Key string `toml:"key"`
+}
+
+// A NamespaceNamingStrategy determines how remote namespaces are named.
+type NamespaceNamingStrategy string
+
+// Namespace naming strategies.
+const (
+	// NamespaceNamingStrategyHash names remote namespaces
+	// <node-name>-<hash of local namespace>.
+	NamespaceNamingStrategyHash NamespaceNamingStrategy = "hash"
+
+	// NamespaceNamingStrategyReadable names remote namespaces
+	// <node-name>-<local namespace>-<hash of both>, truncated if necessary.
+	NamespaceNamingStrategyReadable NamespaceNamingStrategy = "readable"
+
+	// NamespaceNamingStrategyTemplate names remote namespaces using a Go
+	// template.
+	NamespaceNamingStrategyTemplate NamespaceNamingStrategy = "template"
+)
+
+// The NamespacesConfig is used to configure the remote namespaces AK creates.
+type NamespacesConfig struct {
+	// NamingStrategy determines how remote namespaces are named. Defaults to
+	// hash.
+	NamingStrategy NamespaceNamingStrategy `toml:"naming_strategy"`
+
+	// NamingTemplate is a Go template used to name remote namespaces when
+	// using the template strategy. The template may reference .NodeName,
+	// .Namespace (the local namespace), and .Hash (a hash of the local
+	// namespace).
+	NamingTemplate string `toml:"naming_template"`
+
+	// Quota applied to each remote namespace, unless overridden.
+	Quota NamespaceQuotaConfig `toml:"quota"`
+
+	// Overrides of the quota applied to the remote namespaces corresponding
+	// to particular local namespaces, keyed by local namespace name. An
+	// override replaces the default quota entirely; an empty override
+	// exempts a namespace from quota.
+	Overrides map[string]NamespaceQuotaConfig `toml:"overrides"`
+}
+
+// QuotaFor returns the quota that should be applied to the remote namespace
+// corresponding to the supplied local namespace.
+func (c NamespacesConfig) QuotaFor(localNamespace string) NamespaceQuotaConfig {
+	if q, ok := c.Overrides[localNamespace]; ok {
+		return q
+	}
+	return c.Quota
+}
+
+// QuotaConfigured returns true if a quota is configured for any remote
+// namespace, either by default or by override.
+func (c NamespacesConfig) QuotaConfigured() bool {
+	return !c.Quota.IsZero() || len(c.Overrides) > 0
+}
+
+// The NamespaceQuotaConfig is used to configure the ResourceQuota and
+// LimitRange AK applies to a remote namespace. Each is keyed by resource name,
+// with quantity values, e.g. "requests.cpu" = "10".
+type NamespaceQuotaConfig struct {
+	// ResourceQuota hard limits for the remote namespace. No ResourceQuota
+	// is applied if no hard limits are specified.
+	ResourceQuota map[string]string `toml:"resource_quota"`
+
+	// LimitRange constraints for each container in the remote namespace. No
+	// LimitRange is applied if no constraints are specified.
+	LimitRange LimitRangeConfig `toml:"limit_range"`
+}
+
+// IsZero returns true if neither a ResourceQuota nor a LimitRange is
+// configured.
+func (c NamespaceQuotaConfig) IsZero() bool {
+	lr := c.LimitRange
+	return len(c.ResourceQuota) == 0 && len(lr.Default) == 0 && len(lr.DefaultRequest) == 0 && len(lr.Min) == 0 && len(lr.Max) == 0
+}
+
+// The LimitRangeConfig is used to configure the per-container constraints of a
+// LimitRange.
+type LimitRangeConfig struct {
+	// Default limits of containers that specify none.
+	Default map[string]string `toml:"default"`
+
+	// DefaultRequest of containers that specify none.
+	DefaultRequest map[string]string `toml:"default_request"`
+
+	// Min resources a container may request.
+	Min map[string]string `toml:"min"`
+
+	// Max resources a container may be limited to.
+	Max map[string]string `toml:"max"`
+}
+
+// NewNamespaceNamer returns a NamespaceNamer for the supplied namespaces
+// configuration.
+func NewNamespaceNamer(cfg NamespacesConfig) (remote.NamespaceNamer, error) {
+	switch cfg.NamingStrategy {
+	case NamespaceNamingStrategyReadable:
+		return remote.ReadableNamespaceNamer, nil
+	case NamespaceNamingStrategyTemplate:
+		if cfg.NamingTemplate == "" {
+			return nil, errors.New("namespace naming template is required")
+		}
+		n, err := remote.NewTemplateNamespaceNamer(cfg.NamingTemplate)
+		return n, errors.Wrap(err, "invalid namespace naming template")
+	case "", NamespaceNamingStrategyHash:
+		return remote.HashNamespaceNamer, nil
+	default:
+		return nil, errors.Errorf("unknown namespace naming strategy %q", cfg.NamingStrategy)
+	}
+}
+
 // The PodsConfig is used to influence how pods are prepared for submission to
 // the remote API server.
 type PodsConfig struct {
 	// Env vars that should be added to (or overridden in) all pod containers.
 	Env []corev1.EnvVar `toml:"env"`
+
+	// Expose configures how pods are made reachable from the local cluster.
+	Expose PodExposureConfig `toml:"expose"`
+
+	// Mutations that should be applied to remote pods, in order.
+	Mutations []PodMutation `toml:"mutations"`
+
+	// Scheduling configures how pods' scheduling constraints are prepared.
+	Scheduling SchedulingConfig `toml:"scheduling"`
+
+	// PriorityClasses configures how pods' priority classes are handled.
+	PriorityClasses ClassConfig `toml:"priority_classes"`
+
+	// RuntimeClasses configures how pods' runtime classes are handled.
+	RuntimeClasses ClassConfig `toml:"runtime_classes"`
+}
+
+// A ClassMode determines how the cluster scoped classes referenced by a pod are
+// made available in the remote cluster.
+type ClassMode string
+
+// Class modes.
+const (
+	// ClassModeReplicate replicates each local class referenced by a pod to
+	// the remote cluster, prefixed with the node name.
+	ClassModeReplicate ClassMode = "replicate"
+
+	// ClassModeMap maps local class names to remote class names, which must
+	// already exist in the remote cluster.
+	ClassModeMap ClassMode = "map"
+)
+
+// The ClassConfig is used to configure how the cluster scoped classes (i.e.
+// PriorityClasses or RuntimeClasses) referenced by a pod are made available in
+// the remote cluster. Pods reference classes by the same name in the local and
+// remote clusters if no mode is specified.
+type ClassConfig struct {
+	// Mode determines whether classes are replicated or mapped.
+	Mode ClassMode `toml:"mode"`
+
+	// Map of local class names to remote class names. Only used in map mode.
+	// Classes that do not appear in the map are referenced by the same name.
+	Map map[string]string `toml:"map"`
+}
+
+// RemoteName returns the name of the remote class that corresponds to the
+// supplied local class.
+func (c ClassConfig) RemoteName(nodeName, name string) string {
+	switch c.Mode {
+	case ClassModeReplicate:
+		return remote.ClassName(nodeName, name)
+	case ClassModeMap:
+		if n, ok := c.Map[name]; ok {
+			return n
+		}
+	}
+	return name
+}
+
+// A SchedulingMode determines how the scheduling constraints of local pods are
+// prepared for the remote cluster.
+type SchedulingMode string
+
+// Scheduling modes.
+const (
+	// SchedulingModeDrop removes all node selectors, affinity, and topology
+	// spread constraints from remote pods.
+	SchedulingModeDrop SchedulingMode = "drop"
+
+	// SchedulingModeTranslate keeps pod affinity and anti-affinity, with
+	// their namespaces translated to remote namespaces. Node selectors, node
+	// affinity, and topology spread constraints are kept only for allowed
+	// node label keys.
+	SchedulingModeTranslate SchedulingMode = "translate"
+)
+
+// The SchedulingConfig is used to configure how the scheduling constraints of
+// local pods are prepared for the remote cluster.
+type SchedulingConfig struct {
+	// Mode determines whether scheduling constraints are dropped or
+	// translated. Defaults to drop.
+	Mode SchedulingMode `toml:"mode"`
+
+	// NodeLabelKeys that are meaningful in the remote cluster, for example
+	// topology.kubernetes.io/zone. Only used in translate mode.
+	NodeLabelKeys []string `toml:"node_label_keys"`
+}
+
+// A PodMutation is applied to the remote pods corresponding to the local pods
+// it selects. A mutation that specifies neither namespaces nor a selector
+// selects all pods.
+type PodMutation struct {
+	// Namespaces (i.e. local namespaces) of the pods this mutation selects.
+	Namespaces []string `toml:"namespaces"`
+
+	// Selector of the labels of the (local) pods this mutation selects, for
+	// example "app=cool,tier in (web, api)".
+	Selector string `toml:"selector"`
+
+	// Tolerations that should be added to remote pods.
+	Tolerations []corev1.Toleration `toml:"tolerations"`
+
+	// NodeSelector labels that should be added to remote pods.
+	NodeSelector map[string]string `toml:"node_selector"`
+
+	// PriorityClassName that should be set on remote pods.
+	PriorityClassName string `toml:"priority_class_name"`
+
+	// RuntimeClassName that should be set on remote pods.
+	RuntimeClassName string `toml:"runtime_class_name"`
+
+	// Labels that should be added to remote pods.
+	Labels map[string]string `toml:"labels"`
+
+	// Annotations that should be added to remote pods.
+	Annotations map[string]string `toml:"annotations"`
+
+	// Patch is an RFC 6902 JSON patch that should be applied to remote pods,
+	// after any other mutations.
+	Patch string `toml:"patch"`
+}
+
+// The PodExposureConfig is used to configure how remote pods are made
+// reachable from the local cluster. Remote pod IPs are typically not routable
+// from the local cluster, so a pod may instead be exposed by a remote service,
+// in which case the address of that service is reported as its pod IP.
+type PodExposureConfig struct {
+	// Type of the remote services that expose pods. Only LoadBalancer is
+	// supported; a pod's IP can't carry the port mapping of a NodePort
+	// service. Pods are not exposed if no type is specified.
+	Type corev1.ServiceType `toml:"type"`
+
+	// GroupBy is an optional label key. Pods with the same value for this
+	// label are exposed by a single service, rather than a service per pod.
+	GroupBy string `toml:"group_by"`
+}
+
+// The StorageConfig is used to influence how persistent volume claims are
+// prepared for submission to the remote API server.
+type StorageConfig struct {
+	// Classes maps local storage class names to remote storage class names.
+	// Storage classes that are not mapped are passed through as is.
+	Classes map[string]string `toml:"classes"`
+}
+
+// A ServiceMirrorMode determines how local services are mirrored to remote
+// clusters.
+type ServiceMirrorMode string
+
+// Service mirror modes.
+const (
+	// ServiceMirrorModeEndpoints mirrors each local service as a remote
+	// service without a selector, and mirrors its endpoints. Remote pods must
+	// be able to reach local pod IPs.
+	ServiceMirrorModeEndpoints ServiceMirrorMode = "endpoints"
+
+	// ServiceMirrorModeExternalName mirrors each local service as a remote
+	// ExternalName service that resolves to the local service's name within
+	// the configured domain.
+	ServiceMirrorModeExternalName ServiceMirrorMode = "external-name"
+)
+
+// The ServicesConfig is used to configure how local services are mirrored to
+// remote clusters, allowing remote pods to reach local services by name.
+type ServicesConfig struct {
+	// Mirror determines how local services are mirrored. Services are not
+	// mirrored if no mode is specified.
+	Mirror ServiceMirrorMode `toml:"mirror"`
+
+	// Domain within which local services may be resolved from remote
+	// clusters, for example via a DNS stub domain. Mirrored ExternalName
+	// services resolve to <service>.<namespace>.svc.<domain>. Required when
+	// using the external-name mode.
+	Domain string `toml:"domain"`
+}
+
+// The EventsConfig is used to configure how events recorded on remote pods are
+// mirrored to their corresponding local pods.
+type EventsConfig struct {
+	// Mirror events recorded on remote pods to local pods.
+	Mirror bool `toml:"mirror"`
+
+	// QPS at which events may be mirrored from each remote cluster. Events
+	// that would exceed this rate are dropped. Defaults to one.
+	QPS float32 `toml:"qps"`
+
+	// Burst of events that may be mirrored from each remote cluster, above
+	// the configured QPS. Defaults to 25.
+	Burst int `toml:"burst"`
+}
+
+// The MetricsConfig is used to configure how AK exposes Prometheus metrics.
+type MetricsConfig struct {
+	// Port on which to serve metrics at /metrics. Metrics are not served if
+	// no port is specified.
+	Port int `toml:"port"`
+}
+
+// The TracingConfig is used to configure how AK exports OpenTelemetry traces.
+type TracingConfig struct {
+	// Endpoint of an OTLP gRPC collector to which spans should be exported,
+	// e.g. otel-collector:55680. Traces are not exported if no endpoint is
+	// specified.
+	Endpoint string `toml:"endpoint"`
+
+	// Insecure disables TLS when connecting to the collector.
+	Insecure bool `toml:"insecure"`
 }
 
 // The NodeConfig is used to configure how the Node presented to the local API
@@ -44,15 +422,74 @@ type PodsConfig struct {
 type NodeConfig struct {
 	// Resources the Node should indicate it has.
 	Resources NodeResourcesConfig `toml:"resources"`
+
+	// Health configures how the Node determines whether it is ready.
+	Health NodeHealthConfig `toml:"health"`
+}
+
+// The NodeHealthConfig is used to configure how the Node determines whether it
+// is ready. The Node is ready when it can reach any remote API server.
+type NodeHealthConfig struct {
+	// ProbeInterval specifies how frequently the remote API server is probed.
+	// Defaults to ten seconds.
+	ProbeInterval Duration `toml:"probe_interval"`
+
+	// FailureThreshold specifies how many consecutive probes must fail before
+	// the Node reports that it is not ready. Defaults to three.
+	FailureThreshold int `toml:"failure_threshold"`
 }
 
+// A NodeResourcesMode determines how the Node's resources are derived.
+type NodeResourcesMode string
+
+// Node resources modes.
+const (
+	// NodeResourcesModeStatic nodes present the allocatable resources
+	// specified by their config file.
+	NodeResourcesModeStatic NodeResourcesMode = "static"
+
+	// NodeResourcesModeRemote nodes present the resources of the remote
+	// cluster; the allocatable resources of all of its ready nodes, less the
+	// resources requested by the pods running on them.
+	NodeResourcesModeRemote NodeResourcesMode = "remote"
+)
+
 // The NodeResourcesConfig is used to configure the resources the Node will
 // present to the local API server.
 type NodeResourcesConfig struct {
-	// Allocatable resources the Node should indicate it has.
+	// Mode determines how the Node's resources are derived. Defaults to
+	// static.
+	Mode NodeResourcesMode `toml:"mode"`
+
+	// RefreshInterval specifies how frequently the Node's resources are
+	// refreshed when they are derived from the remote cluster. Defaults to one
+	// minute.
+	RefreshInterval Duration `toml:"refresh_interval"`
+
+	// Allocatable resources the Node should indicate it has. In remote mode
+	// these are only used until the remote cluster's resources can first be
+	// determined.
 	Allocatable map[string]string `toml:"allocatable"`
 }
 
+// The GarbageCollectionConfig is used to configure how AK garbage collects the
+// remote namespaces and pod dependencies that are no longer needed by any local
+// pod.
+type GarbageCollectionConfig struct {
+	// Interval at which to garbage collect. Garbage collection is disabled if
+	// no interval is specified.
+	Interval Duration `toml:"interval"`
+
+	// GracePeriod for which a remote object must have existed before it may be
+	// garbage collected. This guards against deleting objects that were
+	// created for a pod that is still being created. Defaults to five minutes.
+	GracePeriod Duration `toml:"grace_period"`
+
+	// DryRun causes the garbage collector to log the objects it would delete,
+	// without actually deleting them.
+	DryRun bool `toml:"dry_run"`
+}
+
 // A ConfigFile is used to configure AK.
 type ConfigFile struct {
 	// Local client configuration - i.e. how AK should connect to the API
@@ -62,13 +499,56 @@ type ConfigFile struct {
 	// Remote client configuration - i.e. the API server in which AK runs pods.
 	Remote ClientConfig `toml:"remote"`
 
+	// Remotes configuration - i.e. the API servers in which AK runs pods, when
+	// there are several. Mutually exclusive with Remote.
+	Remotes []RemoteConfig `toml:"remotes"`
+
+	// Placement configuration - determines which remote API server each pod
+	// is created in when there are several.
+	Placement PlacementConfig `toml:"placement"`
+
+	// Namespaces configuration - influences how remote namespaces are named.
+	Namespaces NamespacesConfig `toml:"namespaces"`
+
 	// Pods configuration - influences how pods are prepared for submission to
 	// the remote API server.
 	Pods PodsConfig `toml:"pods"`
 
+	// Storage configuration - influences how persistent volume claims are
+	// prepared for submission to the remote API server.
+	Storage StorageConfig `toml:"storage"`
+
+	// Services configuration - configures how local services are mirrored to
+	// remote clusters.
+	Services ServicesConfig `toml:"services"`
+
+	// Events configuration - configures how events recorded on remote pods
+	// are mirrored to local pods.
+	Events EventsConfig `toml:"events"`
+
 	// Node configuration - configures how the Node is presented to the local
 	// API server.
 	Node NodeConfig `toml:"node"`
+
+	// Metrics configuration - configures how AK exposes Prometheus metrics.
+	Metrics MetricsConfig `toml:"metrics"`
+
+	// Tracing configuration - configures how AK exports traces.
+	Tracing TracingConfig `toml:"tracing"`
+
+	// GarbageCollection configuration - configures how AK garbage collects
+	// remote objects that are no longer needed.
+	GarbageCollection GarbageCollectionConfig `toml:"gc"`
+}
+
+// RemoteConfigs returns the configuration of all remote API servers. The
+// remote section of the config file is returned as a remote named 'default' if
+// no remotes are configured.
+func (cfg ConfigFile) RemoteConfigs() []RemoteConfig {
+	if len(cfg.Remotes) == 0 {
+		return []RemoteConfig{{Name: DefaultRemoteName, ClientConfig: cfg.Remote}}
+	}
+	return cfg.Remotes
 }
 
 // ParseConfigFile parses the TOML config file at the supplied path.
@@ -92,15 +572,145 @@ func ParseConfigFile(path string) (ConfigFile, error) {
 
 // ValidateConfigFile returns an error if the supplied config is invalid.
 func ValidateConfigFile(cfg ConfigFile) error {
-	if cfg.Remote.KubeConfigPath == "" && cfg.Local.KubeConfigPath == "" {
-		return errors.New("at least one of local or remote kubeconfig path is required")
+	if err := validateRemotes(cfg); err != nil {
+		return err
 	}
 
-	for k, v := range cfg.Node.Resources.Allocatable {
+	if _, err := NewNamespaceNamer(cfg.Namespaces); err != nil {
+		return err
+	}
+
+	if err := validateNamespaceQuota(cfg.Namespaces.Quota); err != nil {
+		return errors.Wrap(err, "invalid namespace quota")
+	}
+
+	for ns, q := range cfg.Namespaces.Overrides {
+		if err := validateNamespaceQuota(q); err != nil {
+			return errors.Wrapf(err, "invalid namespace quota override for namespace %q", ns)
+		}
+	}
+
+	if err := validateQuantities(cfg.Node.Resources.Allocatable); err != nil {
+		return err
+	}
+
+	if _, err := NewPodMutator(cfg.Pods.Mutations); err != nil {
+		return errors.Wrap(err, "invalid pod mutations")
+	}
+
+	switch cfg.Pods.Scheduling.Mode {
+	case "", SchedulingModeDrop, SchedulingModeTranslate:
+	default:
+		return errors.Errorf("unknown scheduling mode %q", cfg.Pods.Scheduling.Mode)
+	}
+
+	for kind, cc := range map[string]ClassConfig{"priority": cfg.Pods.PriorityClasses, "runtime": cfg.Pods.RuntimeClasses} {
+		switch cc.Mode {
+		case "", ClassModeReplicate, ClassModeMap:
+		default:
+			return errors.Errorf("unknown %s class mode %q", kind, cc.Mode)
+		}
+	}
+
+	switch cfg.Pods.Expose.Type {
+	case "", corev1.ServiceTypeLoadBalancer:
+	default:
+		return errors.Errorf("unsupported pod exposure service type %q", cfg.Pods.Expose.Type)
+	}
+
+	switch cfg.Services.Mirror {
+	case "", ServiceMirrorModeEndpoints:
+	case ServiceMirrorModeExternalName:
+		if cfg.Services.Domain == "" {
+			return errors.New("services domain is required when mirroring services as external names")
+		}
+	default:
+		return errors.Errorf("unknown service mirror mode %q", cfg.Services.Mirror)
+	}
+
+	if cfg.Events.QPS < 0 {
+		return errors.New("events qps must not be negative")
+	}
+
+	if cfg.Events.Burst < 0 {
+		return errors.New("events burst must not be negative")
+	}
+
+	switch cfg.Node.Resources.Mode {
+	case "", NodeResourcesModeStatic, NodeResourcesModeRemote:
+	default:
+		return errors.Errorf("unknown node resources mode %q", cfg.Node.Resources.Mode)
+	}
+
+	if cfg.Node.Resources.RefreshInterval.Duration < 0 {
+		return errors.New("node resources refresh interval must not be negative")
+	}
+
+	if cfg.Node.Health.ProbeInterval.Duration < 0 {
+		return errors.New("node health probe interval must not be negative")
+	}
+
+	if cfg.Node.Health.FailureThreshold < 0 {
+		return errors.New("node health failure threshold must not be negative")
+	}
+
+	if cfg.Metrics.Port < 0 || cfg.Metrics.Port > 65535 {
+		return errors.Errorf("invalid metrics port %d", cfg.Metrics.Port)
+	}
+
+	if cfg.GarbageCollection.Interval.Duration < 0 {
+		return errors.New("garbage collection interval must not be negative")
+	}
+
+	if cfg.GarbageCollection.GracePeriod.Duration < 0 {
+		return errors.New("garbage collection grace period must not be negative")
+	}
+
+	return nil
+}
+
+func validateNamespaceQuota(q NamespaceQuotaConfig) error {
+	for _, rl := range []map[string]string{q.ResourceQuota, q.LimitRange.Default, q.LimitRange.DefaultRequest, q.LimitRange.Min, q.LimitRange.Max} {
+		if err := validateQuantities(rl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateQuantities(rl map[string]string) error {
+	for k, v := range rl {
 		if _, err := resource.ParseQuantity(v); err != nil {
 			return errors.Wrapf(err, "cannot parse %q resource quantity", k)
 		}
 	}
+	return nil
+}
+
+func validateRemotes(cfg ConfigFile) error {
+	if cfg.Remote.KubeConfigPath != "" && len(cfg.Remotes) > 0 {
+		return errors.New("remote and remotes are mutually exclusive")
+	}
+
+	names := map[string]bool{}
+	for _, r := range cfg.RemoteConfigs() {
+		if r.KubeConfigPath == "" && cfg.Local.KubeConfigPath == "" {
+			return errors.New("at least one of local or remote kubeconfig path is required")
+		}
+		if r.Name == "" {
+			return errors.New("remote name is required")
+		}
+		if names[r.Name] {
+			return errors.Errorf("remote name %q is not unique", r.Name)
+		}
+		names[r.Name] = true
+	}
+
+	switch cfg.Placement.Policy {
+	case "", PlacementPolicyRoundRobin, PlacementPolicyLeastLoaded, PlacementPolicySelected:
+	default:
+		return errors.Errorf("unknown placement policy %q", cfg.Placement.Policy)
+	}
 
 	return nil
 }
@@ -2,9 +2,11 @@ package kubernetes
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/crossplane/crossplane-runtime/pkg/test"
@@ -24,6 +26,88 @@ func TestValidateConfigFile(t *testing.T) {
 			cfg:    ConfigFile{},
 			want:   errors.New("at least one of local or remote kubeconfig path is required"),
 		},
+		"RemoteAndRemotes": {
+			reason: "Remote and remotes are mutually exclusive",
+			cfg: ConfigFile{
+				Remote:  ClientConfig{KubeConfigPath: "/kcfg"},
+				Remotes: []RemoteConfig{{Name: "a", ClientConfig: ClientConfig{KubeConfigPath: "/kcfg"}}},
+			},
+			want: errors.New("remote and remotes are mutually exclusive"),
+		},
+		"DuplicateRemoteName": {
+			reason: "Remote names must be unique",
+			cfg: ConfigFile{
+				Remotes: []RemoteConfig{
+					{Name: "a", ClientConfig: ClientConfig{KubeConfigPath: "/kcfg"}},
+					{Name: "a", ClientConfig: ClientConfig{KubeConfigPath: "/kcfg"}},
+				},
+			},
+			want: errors.Errorf("remote name %q is not unique", "a"),
+		},
+		"UnknownPlacementPolicy": {
+			reason: "Placement policies must be known",
+			cfg: ConfigFile{
+				Remote:    ClientConfig{KubeConfigPath: "/kcfg"},
+				Placement: PlacementConfig{Policy: "wat"},
+			},
+			want: errors.Errorf("unknown placement policy %q", "wat"),
+		},
+		"UnknownNamespaceNamingStrategy": {
+			reason: "Namespace naming strategies must be known",
+			cfg: ConfigFile{
+				Remote:     ClientConfig{KubeConfigPath: "/kcfg"},
+				Namespaces: NamespacesConfig{NamingStrategy: "wat"},
+			},
+			want: errors.Errorf("unknown namespace naming strategy %q", "wat"),
+		},
+		"UnsupportedPodExposureType": {
+			reason: "Pods may only be exposed by LoadBalancer services",
+			cfg: ConfigFile{
+				Remote: ClientConfig{KubeConfigPath: "/kcfg"},
+				Pods:   PodsConfig{Expose: PodExposureConfig{Type: corev1.ServiceTypeNodePort}},
+			},
+			want: errors.Errorf("unsupported pod exposure service type %q", corev1.ServiceTypeNodePort),
+		},
+		"UnknownServiceMirrorMode": {
+			reason: "Service mirror modes must be known",
+			cfg: ConfigFile{
+				Remote:   ClientConfig{KubeConfigPath: "/kcfg"},
+				Services: ServicesConfig{Mirror: "wat"},
+			},
+			want: errors.Errorf("unknown service mirror mode %q", "wat"),
+		},
+		"NegativeEventsQPS": {
+			reason: "Events QPS must not be negative",
+			cfg: ConfigFile{
+				Remote: ClientConfig{KubeConfigPath: "/kcfg"},
+				Events: EventsConfig{QPS: -1},
+			},
+			want: errors.New("events qps must not be negative"),
+		},
+		"InvalidMetricsPort": {
+			reason: "The metrics port must be a valid port number",
+			cfg: ConfigFile{
+				Remote:  ClientConfig{KubeConfigPath: "/kcfg"},
+				Metrics: MetricsConfig{Port: 65536},
+			},
+			want: errors.Errorf("invalid metrics port %d", 65536),
+		},
+		"MissingServicesDomain": {
+			reason: "A domain is required when mirroring services as external names",
+			cfg: ConfigFile{
+				Remote:   ClientConfig{KubeConfigPath: "/kcfg"},
+				Services: ServicesConfig{Mirror: ServiceMirrorModeExternalName},
+			},
+			want: errors.New("services domain is required when mirroring services as external names"),
+		},
+		"MissingNamespaceNamingTemplate": {
+			reason: "A namespace naming template is required when using the template strategy",
+			cfg: ConfigFile{
+				Remote:     ClientConfig{KubeConfigPath: "/kcfg"},
+				Namespaces: NamespacesConfig{NamingStrategy: NamespaceNamingStrategyTemplate},
+			},
+			want: errors.New("namespace naming template is required"),
+		},
 		"InvalidResourceValue": {
 			reason: "Resource values must be parseable",
 			cfg: ConfigFile{
@@ -36,6 +120,84 @@ func TestValidateConfigFile(t *testing.T) {
 			},
 			want: errors.Wrapf(err, "cannot parse %q resource quantity", rt),
 		},
+		"InvalidNamespaceQuotaValue": {
+			reason: "Namespace quota values must be parseable",
+			cfg: ConfigFile{
+				Remote: ClientConfig{KubeConfigPath: "/kcfg"},
+				Namespaces: NamespacesConfig{
+					Quota: NamespaceQuotaConfig{ResourceQuota: map[string]string{rt: "wat"}},
+				},
+			},
+			want: errors.Wrap(errors.Wrapf(err, "cannot parse %q resource quantity", rt), "invalid namespace quota"),
+		},
+		"InvalidNamespaceQuotaOverrideValue": {
+			reason: "Namespace quota override values must be parseable",
+			cfg: ConfigFile{
+				Remote: ClientConfig{KubeConfigPath: "/kcfg"},
+				Namespaces: NamespacesConfig{
+					Overrides: map[string]NamespaceQuotaConfig{
+						"coolns": {LimitRange: LimitRangeConfig{Max: map[string]string{rt: "wat"}}},
+					},
+				},
+			},
+			want: errors.Wrapf(errors.Wrapf(err, "cannot parse %q resource quantity", rt), "invalid namespace quota override for namespace %q", "coolns"),
+		},
+		"UnknownSchedulingMode": {
+			reason: "Scheduling modes must be known",
+			cfg: ConfigFile{
+				Remote: ClientConfig{KubeConfigPath: "/kcfg"},
+				Pods:   PodsConfig{Scheduling: SchedulingConfig{Mode: "wat"}},
+			},
+			want: errors.Errorf("unknown scheduling mode %q", "wat"),
+		},
+		"UnknownRuntimeClassMode": {
+			reason: "Runtime class modes must be known",
+			cfg: ConfigFile{
+				Remote: ClientConfig{KubeConfigPath: "/kcfg"},
+				Pods:   PodsConfig{RuntimeClasses: ClassConfig{Mode: "wat"}},
+			},
+			want: errors.Errorf("unknown %s class mode %q", "runtime", "wat"),
+		},
+		"UnknownNodeResourcesMode": {
+			reason: "Node resources modes must be known",
+			cfg: ConfigFile{
+				Remote: ClientConfig{KubeConfigPath: "/kcfg"},
+				Node: NodeConfig{
+					Resources: NodeResourcesConfig{Mode: "wat"},
+				},
+			},
+			want: errors.Errorf("unknown node resources mode %q", "wat"),
+		},
+		"NegativeNodeHealthFailureThreshold": {
+			reason: "Node health failure thresholds must not be negative",
+			cfg: ConfigFile{
+				Remote: ClientConfig{KubeConfigPath: "/kcfg"},
+				Node: NodeConfig{
+					Health: NodeHealthConfig{FailureThreshold: -1},
+				},
+			},
+			want: errors.New("node health failure threshold must not be negative"),
+		},
+		"NegativeGarbageCollectionInterval": {
+			reason: "Garbage collection intervals must not be negative",
+			cfg: ConfigFile{
+				Remote: ClientConfig{KubeConfigPath: "/kcfg"},
+				GarbageCollection: GarbageCollectionConfig{
+					Interval: Duration{Duration: -1 * time.Second},
+				},
+			},
+			want: errors.New("garbage collection interval must not be negative"),
+		},
+		"NegativeGarbageCollectionGracePeriod": {
+			reason: "Garbage collection grace periods must not be negative",
+			cfg: ConfigFile{
+				Remote: ClientConfig{KubeConfigPath: "/kcfg"},
+				GarbageCollection: GarbageCollectionConfig{
+					GracePeriod: Duration{Duration: -1 * time.Second},
+				},
+			},
+			want: errors.New("garbage collection grace period must not be negative"),
+		},
 		"ValidConfigFile": {
 			reason: "A valid config file should return no error",
 			cfg: ConfigFile{
@@ -59,3 +221,41 @@ func TestValidateConfigFile(t *testing.T) {
 		})
 	}
 }
+
+func TestDurationUnmarshalText(t *testing.T) {
+	_, err := time.ParseDuration("wat")
+
+	type want struct {
+		d   Duration
+		err error
+	}
+	cases := map[string]struct {
+		reason string
+		text   string
+		want   want
+	}{
+		"ValidDuration": {
+			reason: "A valid duration string should be parsed",
+			text:   "10m",
+			want:   want{d: Duration{Duration: 10 * time.Minute}},
+		},
+		"InvalidDuration": {
+			reason: "An invalid duration string should return an error",
+			text:   "wat",
+			want:   want{err: errors.Wrapf(err, "cannot parse duration %q", "wat")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := Duration{}
+			err := d.UnmarshalText([]byte(tc.text))
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nd.UnmarshalText(...): -want error, +got error: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.d, d); diff != "" {
+				t.Errorf("\n%s\nd.UnmarshalText(...): -want, +got: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 
 	"github.com/pkg/errors"
+	authv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
+	nodev1beta1 "k8s.io/api/node/v1beta1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -22,6 +25,10 @@ const (
 	DependencyKindConfigMap DependencyKind = iota
 	DependencyKindSecret
 	DependencyKindServiceAccountTokenSecret
+	DependencyKindPersistentVolumeClaim
+	DependencyKindProjectedServiceAccountToken
+	DependencyKindPriorityClass
+	DependencyKindRuntimeClass
 )
 
 // A Dependency of a pod.
@@ -48,6 +55,15 @@ func FindPodDependencies(pod *corev1.Pod) []Dependency {
 
 	for _, v := range pod.Spec.Volumes {
 		deps = append(deps, FindVolumeDependencies(v)...)
+
+		// Projected service account tokens are issued by the local API
+		// server and replicated as a secret that is specific to the pod.
+		if remote.HasServiceAccountTokenProjection(v) {
+			deps = append(deps, Dependency{
+				Kind: DependencyKindProjectedServiceAccountToken,
+				Name: remote.ServiceAccountTokenSecretName(pod.GetName(), v.Name),
+			})
+		}
 	}
 
 	cs := make([]corev1.Container, 0, len(pod.Spec.Containers)+len(pod.Spec.InitContainers))
@@ -58,6 +74,14 @@ func FindPodDependencies(pod *corev1.Pod) []Dependency {
 		deps = append(deps, FindContainerDependencies(c)...)
 	}
 
+	if n := pod.Spec.PriorityClassName; n != "" {
+		deps = append(deps, Dependency{Kind: DependencyKindPriorityClass, Name: n})
+	}
+
+	if n := pointer.DerefStringOr(pod.Spec.RuntimeClassName, ""); n != "" {
+		deps = append(deps, Dependency{Kind: DependencyKindRuntimeClass, Name: n})
+	}
+
 	return deps
 }
 
@@ -85,11 +109,45 @@ func FindVolumeDependencies(v corev1.Volume) []Dependency {
 			Name:     v.VolumeSource.Secret.SecretName,
 			Optional: pointer.DerefBoolOr(v.VolumeSource.Secret.Optional, false),
 		}}
+	case v.VolumeSource.PersistentVolumeClaim != nil:
+		return []Dependency{{
+			Kind: DependencyKindPersistentVolumeClaim,
+			Name: v.VolumeSource.PersistentVolumeClaim.ClaimName,
+		}}
+	case v.VolumeSource.Projected != nil:
+		return FindProjectedVolumeDependencies(v.VolumeSource.Projected)
 	}
 
 	return nil
 }
 
+// FindProjectedVolumeDependencies returns all of the config maps and secrets
+// the supplied projected volume depends on to work as expected. Service account
+// token projections are not returned, because they are specific to a pod. They
+// are returned by FindPodDependencies.
+func FindProjectedVolumeDependencies(v *corev1.ProjectedVolumeSource) []Dependency {
+	var deps []Dependency
+
+	for _, src := range v.Sources {
+		switch {
+		case src.ConfigMap != nil:
+			deps = append(deps, Dependency{
+				Kind:     DependencyKindConfigMap,
+				Name:     src.ConfigMap.Name,
+				Optional: pointer.DerefBoolOr(src.ConfigMap.Optional, false),
+			})
+		case src.Secret != nil:
+			deps = append(deps, Dependency{
+				Kind:     DependencyKindSecret,
+				Name:     src.Secret.Name,
+				Optional: pointer.DerefBoolOr(src.Secret.Optional, false),
+			})
+		}
+	}
+
+	return deps
+}
+
 // FindContainerDependencies returns all of the dependencies the supplied
 // container depends on to work as expected.
 func FindContainerDependencies(c corev1.Container) []Dependency {
@@ -142,8 +200,13 @@ type DependencyFetcher interface {
 // An APIDependencyFetcher fetches the dependencies of a particular pod by
 // reading them from the API server.
 type APIDependencyFetcher struct {
-	client client.Reader
-	pod    DependencyFinder
+	client  client.Reader
+	pod     DependencyFinder
+	tokens  TokenIssuer
+	classes map[string]string
+
+	priorityClasses bool
+	runtimeClasses  bool
 }
 
 // A DependencyFinder returns all of the resources the supplied pod depends on
@@ -173,12 +236,42 @@ func WithDependencyFinder(df DependencyFinder) APIDependencyFetcherOption {
 	}
 }
 
+// WithTokenIssuer configures how an APIDependencyFetcher issues projected
+// service account tokens.
+func WithTokenIssuer(ti TokenIssuer) APIDependencyFetcherOption {
+	return func(f *APIDependencyFetcher) {
+		f.tokens = ti
+	}
+}
+
+// WithStorageClasses configures an APIDependencyFetcher to map the storage
+// classes of the persistent volume claims it fetches. Keys are local storage
+// class names, and values are remote storage class names.
+func WithStorageClasses(classes map[string]string) APIDependencyFetcherOption {
+	return func(f *APIDependencyFetcher) {
+		f.classes = classes
+	}
+}
+
+// WithClassReplication configures an APIDependencyFetcher to fetch the priority
+// and/or runtime classes of a pod so that they may be replicated. Classes are
+// not fetched by default, and system priority classes are never fetched.
+func WithClassReplication(priorityClasses, runtimeClasses bool) APIDependencyFetcherOption {
+	return func(f *APIDependencyFetcher) {
+		f.priorityClasses = priorityClasses
+		f.runtimeClasses = runtimeClasses
+	}
+}
+
 // NewAPIDependencyFetcher returns a DependencyFetcher that fetches the
 // dependencies of a particular pod by reading them from the API server.
 func NewAPIDependencyFetcher(c client.Reader, o ...APIDependencyFetcherOption) *APIDependencyFetcher {
 	f := &APIDependencyFetcher{
 		client: c,
 		pod:    DependencyFinderFn(FindPodDependencies),
+		tokens: TokenIssuerFn(func(_ context.Context, _, _ string, _ *authv1.TokenRequest) (*authv1.TokenRequest, error) {
+			return nil, errors.New("no token issuer configured")
+		}),
 	}
 	for _, fn := range o {
 		fn(f)
@@ -194,6 +287,15 @@ func (f *APIDependencyFetcher) Fetch(ctx context.Context, pod *corev1.Pod) ([]ru
 	fetched := make([]runtime.Object, 0, len(d))
 
 	for _, dp := range d {
+		if dp.Kind == DependencyKindProjectedServiceAccountToken {
+			s, err := IssueServiceAccountTokens(ctx, f.tokens, pod, dp.Name)
+			if err != nil {
+				return nil, err
+			}
+			fetched = append(fetched, s)
+			continue
+		}
+
 		var obj runtime.Object
 
 		nn := types.NamespacedName{Namespace: pod.GetNamespace(), Name: dp.Name}
@@ -202,6 +304,20 @@ func (f *APIDependencyFetcher) Fetch(ctx context.Context, pod *corev1.Pod) ([]ru
 			obj = &corev1.Secret{}
 		case DependencyKindConfigMap:
 			obj = &corev1.ConfigMap{}
+		case DependencyKindPersistentVolumeClaim:
+			obj = &corev1.PersistentVolumeClaim{}
+		case DependencyKindPriorityClass:
+			if !f.priorityClasses || remote.IsSystemClass(dp.Name) {
+				continue
+			}
+			obj = &schedulingv1.PriorityClass{}
+			nn.Namespace = ""
+		case DependencyKindRuntimeClass:
+			if !f.runtimeClasses {
+				continue
+			}
+			obj = &nodev1beta1.RuntimeClass{}
+			nn.Namespace = ""
 		}
 
 		if err := f.client.Get(ctx, nn, obj); err != nil {
@@ -211,8 +327,11 @@ func (f *APIDependencyFetcher) Fetch(ctx context.Context, pod *corev1.Pod) ([]ru
 			return nil, errors.Wrap(err, "cannot fetch dependency")
 		}
 
-		if dp.Kind == DependencyKindServiceAccountTokenSecret {
+		switch dp.Kind {
+		case DependencyKindServiceAccountTokenSecret:
 			remote.PrepareServiceAccountTokenSecret(obj.(*corev1.Secret))
+		case DependencyKindPersistentVolumeClaim:
+			remote.PreparePersistentVolumeClaim(obj.(*corev1.PersistentVolumeClaim), f.classes)
 		}
 
 		fetched = append(fetched, obj)
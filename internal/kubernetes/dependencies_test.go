@@ -3,10 +3,14 @@ package kubernetes
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/pkg/errors"
+	authv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
+	nodev1beta1 "k8s.io/api/node/v1beta1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -15,6 +19,7 @@ import (
 
 	"github.com/crossplane/crossplane-runtime/pkg/test"
 
+	"github.com/negz/actual-kubelets/internal/pointer"
 	"github.com/negz/actual-kubelets/internal/remote"
 )
 
@@ -87,6 +92,49 @@ func TestFindPodDependencies(t *testing.T) {
 				},
 			},
 		},
+		"ProjectedServiceAccountToken": {
+			reason: "Should find projected service account tokens",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "coolpod"},
+				Spec: corev1.PodSpec{
+					Volumes: []corev1.Volume{{
+						Name: "coolvolume",
+						VolumeSource: corev1.VolumeSource{
+							Projected: &corev1.ProjectedVolumeSource{
+								Sources: []corev1.VolumeProjection{
+									{ServiceAccountToken: &corev1.ServiceAccountTokenProjection{Path: "token"}},
+								},
+							},
+						},
+					}},
+				},
+			},
+			want: []Dependency{
+				{
+					Kind: DependencyKindProjectedServiceAccountToken,
+					Name: "coolpod-coolvolume-token",
+				},
+			},
+		},
+		"Classes": {
+			reason: "Should find priority and runtime classes",
+			pod: &corev1.Pod{
+				Spec: corev1.PodSpec{
+					PriorityClassName: "high",
+					RuntimeClassName:  pointer.String("gvisor"),
+				},
+			},
+			want: []Dependency{
+				{
+					Kind: DependencyKindPriorityClass,
+					Name: "high",
+				},
+				{
+					Kind: DependencyKindRuntimeClass,
+					Name: "gvisor",
+				},
+			},
+		},
 	}
 
 	for name, tc := range cases {
@@ -206,8 +254,55 @@ func TestFindVolumeDependencies(t *testing.T) {
 				},
 			},
 		},
+		"PersistentVolumeClaim": {
+			reason: "Should find a persistent volume claim",
+			v: corev1.Volume{
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: "coolclaim",
+					},
+				},
+			},
+			want: []Dependency{
+				{
+					Kind: DependencyKindPersistentVolumeClaim,
+					Name: "coolclaim",
+				},
+			},
+		},
+		"Projected": {
+			reason: "Should find the config maps and secrets of a projected volume, but not its service account tokens",
+			v: corev1.Volume{
+				VolumeSource: corev1.VolumeSource{
+					Projected: &corev1.ProjectedVolumeSource{
+						Sources: []corev1.VolumeProjection{
+							{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: requiredConfigMap}}},
+							{Secret: &corev1.SecretProjection{
+								LocalObjectReference: corev1.LocalObjectReference{Name: optionalSecret},
+								Optional: func() *bool {
+									t := true
+									return &t
+								}(),
+							}},
+							{ServiceAccountToken: &corev1.ServiceAccountTokenProjection{Path: "token"}},
+						},
+					},
+				},
+			},
+			want: []Dependency{
+				{
+					Kind: DependencyKindConfigMap,
+					Name: requiredConfigMap,
+				},
+				{
+					Kind:     DependencyKindSecret,
+					Name:     optionalSecret,
+					Optional: true,
+				},
+			},
+		},
 		"NotASecretOrConfigMap": {
-			reason: "Volumes that aren't backed by a config map or a secret should return no dependencies",
+			reason: "Volumes that aren't backed by a config map, secret, or claim should return no dependencies",
 			v:      corev1.Volume{},
 			want:   nil,
 		},
@@ -428,6 +523,22 @@ func TestAPIDependencyFetcher(t *testing.T) {
 	errNotFound := kerrors.NewNotFound(schema.GroupResource{}, "")
 	ns := "coolns"
 	name := "coolname"
+	tokenPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "coolpod"},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: "coolsa",
+			Volumes: []corev1.Volume{{
+				Name: "coolvolume",
+				VolumeSource: corev1.VolumeSource{
+					Projected: &corev1.ProjectedVolumeSource{
+						Sources: []corev1.VolumeProjection{
+							{ServiceAccountToken: &corev1.ServiceAccountTokenProjection{Path: "sub/token", Audience: "coolaudience"}},
+						},
+					},
+				},
+			}},
+		},
+	}
 
 	type args struct {
 		ctx context.Context
@@ -498,6 +609,105 @@ func TestAPIDependencyFetcher(t *testing.T) {
 				err: errors.Wrap(errBoom, "cannot fetch dependency"),
 			},
 		},
+		"IssueTokenError": {
+			reason: "Errors issuing a projected service account token should be returned",
+			c:      &test.MockClient{},
+			o: []APIDependencyFetcherOption{
+				WithTokenIssuer(TokenIssuerFn(func(_ context.Context, _, _ string, _ *authv1.TokenRequest) (*authv1.TokenRequest, error) {
+					return nil, errBoom
+				})),
+			},
+			args: args{
+				pod: tokenPod,
+			},
+			want: want{
+				err: errors.Wrap(errBoom, "cannot issue service account token"),
+			},
+		},
+		"IssueTokenSuccess": {
+			reason: "Projected service account tokens should be issued and returned as a secret",
+			c:      &test.MockClient{},
+			o: []APIDependencyFetcherOption{
+				WithTokenIssuer(TokenIssuerFn(func(_ context.Context, _, sa string, tr *authv1.TokenRequest) (*authv1.TokenRequest, error) {
+					tr.Status.Token = sa + "-" + tr.Spec.Audiences[0]
+					tr.Status.ExpirationTimestamp = metav1.NewTime(time.Unix(3600, 0))
+					return tr, nil
+				})),
+			},
+			args: args{
+				pod: tokenPod,
+			},
+			want: want{
+				o: []runtime.Object{&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: ns,
+						Name:      "coolpod-coolvolume-token",
+						Annotations: map[string]string{
+							remote.AnnotationKeyTokenRotateAfter: "1970-01-01T00:48:00Z",
+						},
+					},
+					Data: map[string][]byte{"sub.token": []byte("coolsa-coolaudience")},
+				}},
+			},
+		},
+		"ClassesNotReplicated": {
+			reason: "Classes should not be fetched unless they're replicated",
+			c:      &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+			o: []APIDependencyFetcherOption{
+				WithDependencyFinder(DependencyFinderFn(func(*corev1.Pod) []Dependency {
+					return []Dependency{{Kind: DependencyKindPriorityClass, Name: name}, {Kind: DependencyKindRuntimeClass, Name: name}}
+				})),
+			},
+			args: args{
+				pod: &corev1.Pod{},
+			},
+			want: want{
+				o: []runtime.Object{},
+			},
+		},
+		"SystemPriorityClass": {
+			reason: "System priority classes should never be fetched",
+			c:      &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+			o: []APIDependencyFetcherOption{
+				WithClassReplication(true, true),
+				WithDependencyFinder(DependencyFinderFn(func(*corev1.Pod) []Dependency {
+					return []Dependency{{Kind: DependencyKindPriorityClass, Name: "system-cluster-critical"}}
+				})),
+			},
+			args: args{
+				pod: &corev1.Pod{},
+			},
+			want: want{
+				o: []runtime.Object{},
+			},
+		},
+		"ReplicatedClasses": {
+			reason: "Replicated classes should be fetched from the cluster scope",
+			c: &test.MockClient{
+				MockGet: func(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+					if key.Namespace != "" {
+						return errBoom
+					}
+					obj.(metav1.Object).SetName(key.Name)
+					return nil
+				},
+			},
+			o: []APIDependencyFetcherOption{
+				WithClassReplication(true, true),
+				WithDependencyFinder(DependencyFinderFn(func(*corev1.Pod) []Dependency {
+					return []Dependency{{Kind: DependencyKindPriorityClass, Name: name}, {Kind: DependencyKindRuntimeClass, Name: name}}
+				})),
+			},
+			args: args{
+				pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns}},
+			},
+			want: want{
+				o: []runtime.Object{
+					&schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: name}},
+					&nodev1beta1.RuntimeClass{ObjectMeta: metav1.ObjectMeta{Name: name}},
+				},
+			},
+		},
 		"GetDependencySuccess": {
 			reason: "Fetched dependencies should be returned, and prepared if they're a service account secret",
 			c: &test.MockClient{
@@ -0,0 +1,181 @@
+package kubernetes
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kcache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+const (
+	defaultEventQPS   = 1
+	defaultEventBurst = 25
+)
+
+// An EventMirror mirrors the events recorded on remote pods to their
+// corresponding local pods, so that they're visible to anyone who can only
+// access the local cluster.
+type EventMirror struct {
+	local    client.Reader
+	remote   client.Reader
+	recorder record.EventRecorder
+	nodeName string
+	limiter  flowcontrol.RateLimiter
+	since    time.Time
+
+	// The count of each remote event when it was last mirrored.
+	mu   sync.Mutex
+	seen map[types.UID]int32
+}
+
+// An EventMirrorOption configures an EventMirror.
+type EventMirrorOption func(*EventMirror)
+
+// WithEventRateLimit configures the rate at which an EventMirror may mirror
+// events. Events that would exceed this rate are dropped.
+func WithEventRateLimit(qps float32, burst int) EventMirrorOption {
+	return func(m *EventMirror) {
+		m.limiter = flowcontrol.NewTokenBucketRateLimiter(qps, burst)
+	}
+}
+
+// NewEventMirror returns an EventMirror that mirrors events recorded on remote
+// pods created on behalf of the supplied node, using the supplied recorder.
+// Only events that occur after the EventMirror is created are mirrored.
+func NewEventMirror(local, remote client.Reader, r record.EventRecorder, nodeName string, o ...EventMirrorOption) *EventMirror {
+	m := &EventMirror{
+		local:    local,
+		remote:   remote,
+		recorder: r,
+		nodeName: nodeName,
+		limiter:  flowcontrol.NewTokenBucketRateLimiter(defaultEventQPS, defaultEventBurst),
+		since:    time.Now(),
+		seen:     map[types.UID]int32{},
+	}
+	for _, fn := range o {
+		fn(m)
+	}
+	return m
+}
+
+// Start mirroring events when the supplied (remote) informers observe that they
+// have been created or updated.
+func (m *EventMirror) Start(ctx context.Context, i cache.Informers) error {
+	inf, err := i.GetInformer(ctx, &corev1.Event{})
+	if err != nil {
+		return errors.Wrap(err, "cannot get informer")
+	}
+	inf.AddEventHandler(kcache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			m.mirror(ctx, obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			m.mirror(ctx, obj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if e, ok := obj.(*corev1.Event); ok {
+				m.forget(e)
+			}
+		},
+	})
+	return nil
+}
+
+func (m *EventMirror) mirror(ctx context.Context, obj interface{}) {
+	e, ok := obj.(*corev1.Event)
+	if !ok {
+		return
+	}
+	if err := m.Mirror(ctx, e); err != nil {
+		log.G(ctx).WithError(err).Debug("cannot mirror remote event")
+	}
+}
+
+// Mirror the supplied remote event to the local pod corresponding to the remote
+// pod it was recorded on. Events are only mirrored if they were recorded in a
+// remote namespace created on behalf of our node, and only once per occurrence;
+// an event is mirrored again only if its count increases.
+func (m *EventMirror) Mirror(ctx context.Context, e *corev1.Event) error {
+	if e.InvolvedObject.Kind != "Pod" || lastOccurred(e).Before(m.since) {
+		return nil
+	}
+
+	ns := &corev1.Namespace{}
+	if err := m.remote.Get(ctx, types.NamespacedName{Name: e.GetNamespace()}, ns); err != nil {
+		return errors.Wrap(resource.IgnoreNotFound(err), "cannot get remote namespace")
+	}
+	if ns.GetLabels()[remote.LabelKeyNodeName] != m.nodeName {
+		return nil
+	}
+
+	if !m.observe(e) {
+		return nil
+	}
+
+	rmt := &corev1.Pod{}
+	if err := m.remote.Get(ctx, types.NamespacedName{Namespace: e.InvolvedObject.Namespace, Name: e.InvolvedObject.Name}, rmt); err != nil {
+		return errors.Wrap(resource.IgnoreNotFound(err), "cannot get remote pod")
+	}
+	remote.RecoverObjectMeta(rmt)
+
+	lcl := &corev1.Pod{}
+	if err := m.local.Get(ctx, types.NamespacedName{Namespace: rmt.GetNamespace(), Name: rmt.GetName()}, lcl); err != nil {
+		return errors.Wrap(resource.IgnoreNotFound(err), "cannot get local pod")
+	}
+
+	if !m.limiter.TryAccept() {
+		log.G(ctx).WithField("reason", e.Reason).Debug("dropping remote event due to rate limiting")
+		return nil
+	}
+
+	m.recorder.Event(lcl, e.Type, e.Reason, e.Message)
+	return nil
+}
+
+// observe records that the supplied event has been seen, returning false if it
+// has already been mirrored.
+func (m *EventMirror) observe(e *corev1.Event) bool {
+	count := e.Count
+	if count == 0 {
+		count = 1
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.seen[e.GetUID()] >= count {
+		return false
+	}
+	m.seen[e.GetUID()] = count
+	return true
+}
+
+func (m *EventMirror) forget(e *corev1.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.seen, e.GetUID())
+}
+
+// lastOccurred returns the time at which the supplied event last occurred.
+func lastOccurred(e *corev1.Event) time.Time {
+	switch {
+	case !e.LastTimestamp.IsZero():
+		return e.LastTimestamp.Time
+	case !e.EventTime.IsZero():
+		return e.EventTime.Time
+	default:
+		return e.GetCreationTimestamp().Time
+	}
+}
@@ -0,0 +1,139 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+func TestEventMirrorMirror(t *testing.T) {
+	errBoom := errors.New("boom")
+	nodeName := "coolnode"
+	ns := "coolns"
+	rns := remote.NamespaceName(nodeName, ns)
+
+	event := func(kind string, last time.Duration) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Namespace: rns, UID: "cool-uid"},
+			InvolvedObject: corev1.ObjectReference{Kind: kind, Namespace: rns, Name: "coolpod"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "BackOff",
+			Message:        "Back-off pulling image",
+			LastTimestamp:  metav1.NewTime(time.Now().Add(last)),
+		}
+	}
+	rmt := func(node string) client.Reader {
+		return &test.MockClient{
+			MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+				labels := map[string]string{remote.LabelKeyNodeName: node, remote.LabelKeyNamespace: ns}
+				switch o := obj.(type) {
+				case *corev1.Namespace:
+					o.SetLabels(labels)
+				case *corev1.Pod:
+					o.SetNamespace(rns)
+					o.SetName("coolpod")
+					o.SetLabels(labels)
+				}
+				return nil
+			},
+		}
+	}
+
+	type want struct {
+		events []string
+		err    error
+	}
+	cases := map[string]struct {
+		reason string
+		local  client.Reader
+		remote client.Reader
+		o      []EventMirrorOption
+		events []*corev1.Event
+		want   want
+	}{
+		"NotPod": {
+			reason: "Events that don't involve a pod should not be mirrored",
+			events: []*corev1.Event{event("Service", time.Hour)},
+		},
+		"OccurredBeforeStart": {
+			reason: "Events that last occurred before the mirror was started should not be mirrored",
+			events: []*corev1.Event{event("Pod", -time.Hour)},
+		},
+		"GetNamespaceError": {
+			reason: "Errors getting the remote namespace should be returned",
+			remote: &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+			events: []*corev1.Event{event("Pod", time.Hour)},
+			want: want{
+				err: errors.Wrap(errBoom, "cannot get remote namespace"),
+			},
+		},
+		"OtherNode": {
+			reason: "Events in remote namespaces created on behalf of another node should not be mirrored",
+			remote: rmt("othernode"),
+			events: []*corev1.Event{event("Pod", time.Hour)},
+		},
+		"Mirrored": {
+			reason: "Each occurrence of an event should be mirrored once",
+			local:  &test.MockClient{MockGet: test.NewMockGetFn(nil)},
+			remote: rmt(nodeName),
+			events: []*corev1.Event{event("Pod", time.Hour), event("Pod", time.Hour)},
+			want: want{
+				events: []string{"Warning BackOff Back-off pulling image"},
+			},
+		},
+		"RateLimited": {
+			reason: "Events that exceed the rate limit should be dropped",
+			local:  &test.MockClient{MockGet: test.NewMockGetFn(nil)},
+			remote: rmt(nodeName),
+			o:      []EventMirrorOption{WithEventRateLimit(0.001, 1)},
+			events: func() []*corev1.Event {
+				e := event("Pod", time.Hour)
+				e2 := e.DeepCopy()
+				e2.Count = 2
+				return []*corev1.Event{e, e2}
+			}(),
+			want: want{
+				events: []string{"Warning BackOff Back-off pulling image"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := record.NewFakeRecorder(len(tc.events))
+			m := NewEventMirror(tc.local, tc.remote, r, nodeName, tc.o...)
+
+			var err error
+			for _, e := range tc.events {
+				if err = m.Mirror(context.Background(), e); err != nil {
+					break
+				}
+			}
+			close(r.Events)
+
+			var got []string
+			for e := range r.Events {
+				got = append(got, e)
+			}
+
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nm.Mirror(...): -want error, +got error: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.events, got); diff != "" {
+				t.Errorf("\n%s\nm.Mirror(...): -want events, +got events: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package kubernetes
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+// A PodExposer makes remote pods reachable from the local cluster by exposing
+// them via remote LoadBalancer services, and reporting the address of those
+// services as the pods' IPs.
+type PodExposer struct {
+	serviceType corev1.ServiceType
+	groupBy     string
+}
+
+// NewPodExposer returns a PodExposer that exposes pods as configured.
+func NewPodExposer(cfg PodExposureConfig) *PodExposer {
+	return &PodExposer{serviceType: cfg.Type, groupBy: cfg.GroupBy}
+}
+
+// PreparePod labels the supplied remote pod such that it will be selected by
+// its exposing service. Pods must be prepared before they are created. Pods
+// that don't expose any container ports are not labelled.
+func (e *PodExposer) PreparePod(pod *corev1.Pod) {
+	if !hasContainerPorts(pod) {
+		return
+	}
+	meta.AddLabels(pod, map[string]string{remote.LabelKeyExposingService: remote.ExposingServiceName(pod, e.groupBy)})
+}
+
+// Expose the supplied remote pod by applying its exposing service. Pods that
+// are exposed by their own service own it, and thus it is garbage collected by
+// the remote cluster when they are deleted. Pods that were not labelled by
+// PreparePod are not exposed.
+func (e *PodExposer) Expose(ctx context.Context, a resource.Applicator, pod *corev1.Pod) error {
+	if _, ok := pod.GetLabels()[remote.LabelKeyExposingService]; !ok {
+		return nil
+	}
+	s := remote.ExposingService(pod, e.serviceType)
+	if _, grouped := pod.GetLabels()[e.groupBy]; !grouped || e.groupBy == "" {
+		s.SetOwnerReferences([]metav1.OwnerReference{meta.AsOwner(meta.ReferenceTo(pod, corev1.SchemeGroupVersion.WithKind("Pod")))})
+	}
+	return errors.Wrap(a.Apply(ctx, s, preserveClusterIP), "cannot apply remote exposing service")
+}
+
+func hasContainerPorts(pod *corev1.Pod) bool {
+	for _, c := range pod.Spec.Containers {
+		if len(c.Ports) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// RecoverPodIP replaces the IP of the supplied remote pod with the address at
+// which it is exposed to the local cluster, if any. LoadBalancer services
+// expose pods at their ingress IP. Pods that have not yet been exposed report
+// no pod IP, because their remote pod IP is not routable from the local
+// cluster.
+func (e *PodExposer) RecoverPodIP(ctx context.Context, c client.Reader, pod *corev1.Pod) error {
+	name, ok := pod.GetLabels()[remote.LabelKeyExposingService]
+	if !ok {
+		return nil
+	}
+
+	pod.Status.PodIP = ""
+	pod.Status.PodIPs = nil
+
+	s := &corev1.Service{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: pod.GetNamespace(), Name: name}, s); err != nil {
+		return errors.Wrap(resource.IgnoreNotFound(err), "cannot get remote exposing service")
+	}
+
+	ip := ""
+	for _, i := range s.Status.LoadBalancer.Ingress {
+		if i.IP != "" {
+			ip = i.IP
+			break
+		}
+	}
+
+	if ip == "" {
+		return nil
+	}
+	pod.Status.PodIP = ip
+	pod.Status.PodIPs = []corev1.PodIP{{IP: ip}}
+	return nil
+}
@@ -0,0 +1,111 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+func TestPodExposerRecoverPodIP(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	exposed := func() *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{remote.LabelKeyExposingService: "ak-pod-cool"}},
+			Status: corev1.PodStatus{
+				PodIP:  "192.168.0.1",
+				PodIPs: []corev1.PodIP{{IP: "192.168.0.1"}},
+			},
+		}
+	}
+	withIP := func(pod *corev1.Pod, ip string) *corev1.Pod {
+		pod.Status.PodIP = ip
+		pod.Status.PodIPs = []corev1.PodIP{{IP: ip}}
+		return pod
+	}
+	withoutIP := func(pod *corev1.Pod) *corev1.Pod {
+		pod.Status.PodIP = ""
+		pod.Status.PodIPs = nil
+		return pod
+	}
+	service := func(s corev1.Service) client.Reader {
+		return &test.MockClient{
+			MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+				s.DeepCopyInto(obj.(*corev1.Service))
+				return nil
+			},
+		}
+	}
+
+	type want struct {
+		pod *corev1.Pod
+		err error
+	}
+	cases := map[string]struct {
+		reason string
+		c      client.Reader
+		pod    *corev1.Pod
+		want   want
+	}{
+		"NotExposed": {
+			reason: "Pods that are not exposed should report their pod IP as is",
+			pod:    &corev1.Pod{Status: corev1.PodStatus{PodIP: "192.168.0.1"}},
+			want:   want{pod: &corev1.Pod{Status: corev1.PodStatus{PodIP: "192.168.0.1"}}},
+		},
+		"ServiceNotFound": {
+			reason: "Pods whose exposing service does not exist yet should report no pod IP",
+			c:      &test.MockClient{MockGet: test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{}, "ak-pod-cool"))},
+			pod:    exposed(),
+			want:   want{pod: withoutIP(exposed())},
+		},
+		"GetServiceError": {
+			reason: "Errors getting the exposing service should be returned",
+			c:      &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+			pod:    exposed(),
+			want: want{
+				pod: withoutIP(exposed()),
+				err: errors.Wrap(errBoom, "cannot get remote exposing service"),
+			},
+		},
+		"LoadBalancerPending": {
+			reason: "Pods whose load balancer has no ingress IP yet should report no pod IP",
+			c:      service(corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer}}),
+			pod:    exposed(),
+			want:   want{pod: withoutIP(exposed())},
+		},
+		"LoadBalancer": {
+			reason: "Pods exposed by a load balancer should report its ingress IP",
+			c: service(corev1.Service{
+				Spec:   corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+				Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{{IP: "5.6.7.8"}}}},
+			}),
+			pod:  exposed(),
+			want: want{pod: withIP(exposed(), "5.6.7.8")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := NewPodExposer(PodExposureConfig{Type: corev1.ServiceTypeLoadBalancer})
+			err := e.RecoverPodIP(context.Background(), tc.c, tc.pod)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.RecoverPodIP(...): -want error, +got error: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.pod, tc.pod); diff != "" {
+				t.Errorf("\n%s\ne.RecoverPodIP(...): -want, +got: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,407 @@
+package kubernetes
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	corev1 "k8s.io/api/core/v1"
+	nodev1beta1 "k8s.io/api/node/v1beta1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+const (
+	defaultCollectionInterval = 10 * time.Minute
+	defaultGracePeriod        = 5 * time.Minute
+)
+
+// A GarbageCollector deletes objects from the remote API server that are no
+// longer needed by any local pod scheduled to its node. It deletes remote
+// namespaces that contain no pods, remote config maps and secrets that are not
+// depended on by any local pod, remote persistent volume claims whose local
+// claim no longer exists, remote services that expose no remote pod or mirror
+// no local service, and optionally replicated priority and runtime classes
+// that no local pod uses.
+type GarbageCollector struct {
+	local    client.Reader
+	remote   client.Client
+	nodeName string
+
+	interval        time.Duration
+	grace           time.Duration
+	dryRun          bool
+	priorityClasses bool
+	runtimeClasses  bool
+}
+
+// A GarbageCollectorOption configures the supplied GarbageCollector.
+type GarbageCollectorOption func(*GarbageCollector)
+
+// WithCollectionInterval configures how frequently a GarbageCollector collects
+// garbage.
+func WithCollectionInterval(i time.Duration) GarbageCollectorOption {
+	return func(gc *GarbageCollector) {
+		gc.interval = i
+	}
+}
+
+// WithGracePeriod configures how long a remote object must have existed before
+// a GarbageCollector will consider deleting it.
+func WithGracePeriod(p time.Duration) GarbageCollectorOption {
+	return func(gc *GarbageCollector) {
+		gc.grace = p
+	}
+}
+
+// WithDryRun configures a GarbageCollector to log the objects it would delete
+// rather than actually deleting them.
+func WithDryRun(dryRun bool) GarbageCollectorOption {
+	return func(gc *GarbageCollector) {
+		gc.dryRun = dryRun
+	}
+}
+
+// WithClassCollection configures a GarbageCollector to delete the priority
+// and/or runtime classes replicated to the remote cluster on behalf of its node
+// when no local pod uses them. Classes that are mapped to existing remote
+// classes are not created by AK, and thus are never deleted.
+func WithClassCollection(priorityClasses, runtimeClasses bool) GarbageCollectorOption {
+	return func(gc *GarbageCollector) {
+		gc.priorityClasses = priorityClasses
+		gc.runtimeClasses = runtimeClasses
+	}
+}
+
+// NewGarbageCollector returns a GarbageCollector that deletes objects created
+// in the remote API server on behalf of the supplied node when they are no
+// longer needed by any local pod.
+func NewGarbageCollector(local client.Reader, remote client.Client, nodeName string, o ...GarbageCollectorOption) *GarbageCollector {
+	gc := &GarbageCollector{
+		local:    local,
+		remote:   remote,
+		nodeName: nodeName,
+		interval: defaultCollectionInterval,
+		grace:    defaultGracePeriod,
+	}
+	for _, fn := range o {
+		fn(gc)
+	}
+	return gc
+}
+
+// Run the GarbageCollector, collecting garbage at its configured interval until
+// the supplied context is done.
+func (gc *GarbageCollector) Run(ctx context.Context) {
+	t := time.NewTicker(gc.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := gc.Collect(ctx); err != nil {
+				log.G(ctx).WithError(err).Error("cannot collect remote garbage")
+			}
+		}
+	}
+}
+
+// Collect garbage. Remote namespaces are deleted if they contain no remote pods
+// or persistent volume claims and correspond to a local namespace that contains
+// no pods scheduled to our node. Remote config maps and secrets are deleted if
+// no local pod scheduled to our node depends on them. Remote persistent volume
+// claims are deleted if their local claim no longer exists. Remote services
+// that expose pods are deleted if they select no remote pod, and those that
+// mirror local services are deleted if their local service no longer exists.
+// Replicated remote classes are deleted if no local pod scheduled to our node
+// uses them.
+func (gc *GarbageCollector) Collect(ctx context.Context) error {
+	inUse, err := gc.dependenciesInUse(ctx)
+	if err != nil {
+		return err
+	}
+
+	nl := &corev1.NamespaceList{}
+	if err := gc.remote.List(ctx, nl, client.MatchingLabels{remote.LabelKeyNodeName: gc.nodeName}); err != nil {
+		return errors.Wrap(err, "cannot list remote namespaces")
+	}
+
+	for i := range nl.Items {
+		ns := &nl.Items[i]
+		deps, ok := inUse[ns.GetLabels()[remote.LabelKeyNamespace]]
+		if !ok {
+			if err := gc.collectNamespace(ctx, ns); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := gc.collectDependencies(ctx, ns, deps); err != nil {
+			return err
+		}
+	}
+
+	return gc.collectClasses(ctx, inUse)
+}
+
+// dependenciesInUse returns the dependencies of all local pods scheduled to our
+// node, keyed by local namespace.
+func (gc *GarbageCollector) dependenciesInUse(ctx context.Context) (map[string]map[dependencyKey]bool, error) {
+	pl := &corev1.PodList{}
+	if err := gc.local.List(ctx, pl); err != nil {
+		return nil, errors.Wrap(err, "cannot list local pods")
+	}
+
+	inUse := map[string]map[dependencyKey]bool{}
+	for i := range pl.Items {
+		pod := &pl.Items[i]
+		if pod.Spec.NodeName != gc.nodeName {
+			continue
+		}
+		if inUse[pod.GetNamespace()] == nil {
+			inUse[pod.GetNamespace()] = map[dependencyKey]bool{}
+		}
+		for _, d := range FindPodDependencies(pod) {
+			inUse[pod.GetNamespace()][keyFor(d)] = true
+		}
+	}
+
+	return inUse, nil
+}
+
+func (gc *GarbageCollector) collectNamespace(ctx context.Context, ns *corev1.Namespace) error {
+	pl := &corev1.PodList{}
+	if err := gc.remote.List(ctx, pl, client.InNamespace(ns.GetName())); err != nil {
+		return errors.Wrap(err, "cannot list remote pods")
+	}
+
+	// The remote namespace still contains pods, presumably because they have
+	// been deleted locally but are still terminating remotely.
+	if len(pl.Items) > 0 {
+		return nil
+	}
+
+	// The remote namespace still contains persistent volume claims that exist
+	// locally. Deleting the namespace would delete them, and likely their
+	// data, while their local claims still exist.
+	remaining, err := gc.collectClaims(ctx, ns)
+	if err != nil {
+		return err
+	}
+	if remaining > 0 {
+		return nil
+	}
+
+	return gc.delete(ctx, ns)
+}
+
+func (gc *GarbageCollector) collectDependencies(ctx context.Context, ns *corev1.Namespace, inUse map[dependencyKey]bool) error {
+	namespace := ns.GetName()
+	cml := &corev1.ConfigMapList{}
+	if err := gc.remote.List(ctx, cml, client.InNamespace(namespace), client.MatchingLabels{remote.LabelKeyNodeName: gc.nodeName}); err != nil {
+		return errors.Wrap(err, "cannot list remote config maps")
+	}
+	for i := range cml.Items {
+		cm := &cml.Items[i]
+		if inUse[dependencyKey{kind: DependencyKindConfigMap, name: cm.GetName()}] {
+			continue
+		}
+		if err := gc.delete(ctx, cm); err != nil {
+			return err
+		}
+	}
+
+	sl := &corev1.SecretList{}
+	if err := gc.remote.List(ctx, sl, client.InNamespace(namespace), client.MatchingLabels{remote.LabelKeyNodeName: gc.nodeName}); err != nil {
+		return errors.Wrap(err, "cannot list remote secrets")
+	}
+	for i := range sl.Items {
+		s := &sl.Items[i]
+		if inUse[dependencyKey{kind: DependencyKindSecret, name: s.GetName()}] {
+			continue
+		}
+		if err := gc.delete(ctx, s); err != nil {
+			return err
+		}
+	}
+
+	if err := gc.collectServices(ctx, ns); err != nil {
+		return err
+	}
+
+	_, err := gc.collectClaims(ctx, ns)
+	return err
+}
+
+// collectServices deletes the remote services in the supplied remote namespace
+// that expose pods but no longer select any remote pod, or that mirror a local
+// service that no longer exists. A service that exposes a single pod is owned
+// by that pod and thus garbage collected by the remote cluster, but a service
+// that exposes a group of pods is not owned by any of them. A mirrored service
+// is usually deleted when its local service is, but not if its local service
+// was deleted while AK wasn't running. The remote cluster deletes the
+// endpoints of a deleted service.
+func (gc *GarbageCollector) collectServices(ctx context.Context, ns *corev1.Namespace) error {
+	sl := &corev1.ServiceList{}
+	if err := gc.remote.List(ctx, sl, client.InNamespace(ns.GetName()), client.MatchingLabels{remote.LabelKeyNodeName: gc.nodeName}); err != nil {
+		return errors.Wrap(err, "cannot list remote services")
+	}
+
+	pl := &corev1.PodList{}
+	if err := gc.remote.List(ctx, pl, client.InNamespace(ns.GetName())); err != nil {
+		return errors.Wrap(err, "cannot list remote pods")
+	}
+	exposed := map[string]bool{}
+	for _, pod := range pl.Items {
+		if name, ok := pod.GetLabels()[remote.LabelKeyExposingService]; ok {
+			exposed[name] = true
+		}
+	}
+
+	for i := range sl.Items {
+		s := &sl.Items[i]
+		if name, ok := s.Spec.Selector[remote.LabelKeyExposingService]; ok {
+			if exposed[name] {
+				continue
+			}
+			if err := gc.delete(ctx, s); err != nil {
+				return err
+			}
+			continue
+		}
+
+		nn := types.NamespacedName{Namespace: ns.GetLabels()[remote.LabelKeyNamespace], Name: s.GetName()}
+		err := gc.local.Get(ctx, nn, &corev1.Service{})
+		if err == nil {
+			continue
+		}
+		if !kerrors.IsNotFound(err) {
+			return errors.Wrap(err, "cannot get local service")
+		}
+		if err := gc.delete(ctx, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectClasses deletes the priority and runtime classes replicated to the
+// remote cluster on behalf of our node that no local pod scheduled to our node
+// uses, if configured to do so.
+func (gc *GarbageCollector) collectClasses(ctx context.Context, inUse map[string]map[dependencyKey]bool) error {
+	used := map[dependencyKey]bool{}
+	for _, deps := range inUse {
+		for k := range deps {
+			if k.kind == DependencyKindPriorityClass || k.kind == DependencyKindRuntimeClass {
+				used[dependencyKey{kind: k.kind, name: remote.ClassName(gc.nodeName, k.name)}] = true
+			}
+		}
+	}
+
+	if gc.priorityClasses {
+		l := &schedulingv1.PriorityClassList{}
+		if err := gc.remote.List(ctx, l, client.MatchingLabels{remote.LabelKeyNodeName: gc.nodeName}); err != nil {
+			return errors.Wrap(err, "cannot list remote priority classes")
+		}
+		for i := range l.Items {
+			pc := &l.Items[i]
+			if used[dependencyKey{kind: DependencyKindPriorityClass, name: pc.GetName()}] {
+				continue
+			}
+			if err := gc.delete(ctx, pc); err != nil {
+				return err
+			}
+		}
+	}
+
+	if gc.runtimeClasses {
+		l := &nodev1beta1.RuntimeClassList{}
+		if err := gc.remote.List(ctx, l, client.MatchingLabels{remote.LabelKeyNodeName: gc.nodeName}); err != nil {
+			return errors.Wrap(err, "cannot list remote runtime classes")
+		}
+		for i := range l.Items {
+			rc := &l.Items[i]
+			if used[dependencyKey{kind: DependencyKindRuntimeClass, name: rc.GetName()}] {
+				continue
+			}
+			if err := gc.delete(ctx, rc); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// collectClaims deletes the remote persistent volume claims in the supplied
+// remote namespace whose local claim no longer exists. Claims usually outlive
+// the pods that use them, so unlike other dependencies they are not deleted
+// just because no local pod depends on them. collectClaims returns the number
+// of remote claims whose local claim still exists.
+func (gc *GarbageCollector) collectClaims(ctx context.Context, ns *corev1.Namespace) (int, error) {
+	cl := &corev1.PersistentVolumeClaimList{}
+	if err := gc.remote.List(ctx, cl, client.InNamespace(ns.GetName()), client.MatchingLabels{remote.LabelKeyNodeName: gc.nodeName}); err != nil {
+		return 0, errors.Wrap(err, "cannot list remote persistent volume claims")
+	}
+
+	remaining := 0
+	for i := range cl.Items {
+		c := &cl.Items[i]
+		nn := types.NamespacedName{Namespace: ns.GetLabels()[remote.LabelKeyNamespace], Name: c.GetName()}
+		err := gc.local.Get(ctx, nn, &corev1.PersistentVolumeClaim{})
+		if err == nil {
+			remaining++
+			continue
+		}
+		if !kerrors.IsNotFound(err) {
+			return 0, errors.Wrap(err, "cannot get local persistent volume claim")
+		}
+		if err := gc.delete(ctx, c); err != nil {
+			return 0, err
+		}
+	}
+
+	return remaining, nil
+}
+
+func (gc *GarbageCollector) delete(ctx context.Context, obj resource.Object) error {
+	if time.Since(obj.GetCreationTimestamp().Time) < gc.grace {
+		return nil
+	}
+
+	l := log.G(ctx).WithField("namespace", obj.GetNamespace()).WithField("name", obj.GetName())
+	if gc.dryRun {
+		l.Info("would garbage collect remote object (dry run)")
+		return nil
+	}
+
+	l.Info("garbage collecting remote object")
+	if err := gc.remote.Delete(ctx, obj); err != nil && !kerrors.IsNotFound(err) {
+		return errors.Wrap(err, "cannot delete remote object")
+	}
+	return nil
+}
+
+// A dependencyKey identifies a dependency within a namespace.
+type dependencyKey struct {
+	kind DependencyKind
+	name string
+}
+
+func keyFor(d Dependency) dependencyKey {
+	k := d.Kind
+	// Service account token secrets and projected service account tokens are
+	// still secrets.
+	if k == DependencyKindServiceAccountTokenSecret || k == DependencyKindProjectedServiceAccountToken {
+		k = DependencyKindSecret
+	}
+	return dependencyKey{kind: k, name: d.Name}
+}
@@ -0,0 +1,287 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	nodev1beta1 "k8s.io/api/node/v1beta1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/negz/actual-kubelets/internal/pointer"
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+func TestGarbageCollectorCollect(t *testing.T) {
+	errBoom := errors.New("boom")
+	nodeName := "coolnode"
+	localNs := "coolns"
+	remoteNs := remote.NamespaceName(nodeName, localNs)
+	old := metav1.NewTime(time.Unix(0, 0))
+
+	localPod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: localNs, Name: "coolpod"},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Volumes: []corev1.Volume{{
+				VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "used"},
+					},
+				},
+			}},
+		},
+	}
+	otherNodePod := localPod
+	otherNodePod.Spec.NodeName = "othernode"
+
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:              remoteNs,
+		CreationTimestamp: old,
+		Labels:            map[string]string{remote.LabelKeyNodeName: nodeName, remote.LabelKeyNamespace: localNs},
+	}}
+	newNs := ns
+	newNs.SetCreationTimestamp(metav1.Now())
+
+	used := corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: remoteNs, Name: "used", CreationTimestamp: old}}
+	unused := corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: remoteNs, Name: "unused", CreationTimestamp: old}}
+	unusedSecret := corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: remoteNs, Name: "unused", CreationTimestamp: old}}
+
+	exposing := func(name string) corev1.Service {
+		return corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: remoteNs, Name: name, CreationTimestamp: old},
+			Spec:       corev1.ServiceSpec{Selector: map[string]string{remote.LabelKeyExposingService: name}},
+		}
+	}
+	mirrored := func(name string) corev1.Service {
+		return corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: remoteNs, Name: name, CreationTimestamp: old}}
+	}
+	classPod := localPod
+	classPod.Spec.PriorityClassName = "used"
+	classPod.Spec.RuntimeClassName = pointer.String("used")
+	classMeta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: remote.ClassName(nodeName, name), CreationTimestamp: old}
+	}
+
+	exposedPod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace: remoteNs,
+		Name:      "coolpod",
+		Labels:    map[string]string{remote.LabelKeyExposingService: "exposing"},
+	}}
+
+	claim := corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: remoteNs, Name: "coolclaim", CreationTimestamp: old}}
+
+	type objects struct {
+		localPods   []corev1.Pod
+		localClaims map[string]bool
+		localSvcs   map[string]bool
+		namespaces  []corev1.Namespace
+		remotePods  []corev1.Pod
+		configMaps  []corev1.ConfigMap
+		secrets     []corev1.Secret
+		claims      []corev1.PersistentVolumeClaim
+		services    []corev1.Service
+		priorities  []schedulingv1.PriorityClass
+		runtimes    []nodev1beta1.RuntimeClass
+	}
+	type want struct {
+		deleted []string
+		err     error
+	}
+	cases := map[string]struct {
+		reason  string
+		objects objects
+		listErr error
+		o       []GarbageCollectorOption
+		want    want
+	}{
+		"ListError": {
+			reason:  "Errors listing local pods should be returned",
+			listErr: errBoom,
+			want: want{
+				err: errors.Wrap(errBoom, "cannot list local pods"),
+			},
+		},
+		"DeleteEmptyNamespace": {
+			reason: "A remote namespace with no local or remote pods should be deleted",
+			objects: objects{
+				localPods:  []corev1.Pod{otherNodePod},
+				namespaces: []corev1.Namespace{ns},
+			},
+			want: want{
+				deleted: []string{remoteNs},
+			},
+		},
+		"KeepNamespaceWithRemotePods": {
+			reason: "A remote namespace that still contains remote pods should not be deleted",
+			objects: objects{
+				namespaces: []corev1.Namespace{ns},
+				remotePods: []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Namespace: remoteNs, Name: "coolpod"}}},
+			},
+		},
+		"KeepNewNamespace": {
+			reason: "A remote namespace that was created within the grace period should not be deleted",
+			objects: objects{
+				namespaces: []corev1.Namespace{newNs},
+			},
+		},
+		"DryRun": {
+			reason: "Nothing should be deleted in dry run mode",
+			objects: objects{
+				namespaces: []corev1.Namespace{ns},
+			},
+			o: []GarbageCollectorOption{WithDryRun(true)},
+		},
+		"KeepNamespaceWithClaims": {
+			reason: "A remote namespace that contains claims whose local claim still exists should not be deleted",
+			objects: objects{
+				localClaims: map[string]bool{"coolclaim": true},
+				namespaces:  []corev1.Namespace{ns},
+				claims:      []corev1.PersistentVolumeClaim{claim},
+			},
+		},
+		"DeleteOrphanedClaims": {
+			reason: "Remote claims whose local claim no longer exists should be deleted, along with their empty namespace",
+			objects: objects{
+				namespaces: []corev1.Namespace{ns},
+				claims:     []corev1.PersistentVolumeClaim{claim},
+			},
+			want: want{
+				deleted: []string{"coolclaim", remoteNs},
+			},
+		},
+		"DeleteUnusedDependencies": {
+			reason: "Remote dependencies that no local pod depends on should be deleted",
+			objects: objects{
+				localPods:  []corev1.Pod{localPod},
+				namespaces: []corev1.Namespace{ns},
+				configMaps: []corev1.ConfigMap{used, unused},
+				secrets:    []corev1.Secret{unusedSecret},
+			},
+			want: want{
+				deleted: []string{"unused", "unused"},
+			},
+		},
+		"DeleteUnusedExposingServices": {
+			reason: "Remote services that expose no remote pod should be deleted",
+			objects: objects{
+				localPods:  []corev1.Pod{localPod},
+				namespaces: []corev1.Namespace{ns},
+				remotePods: []corev1.Pod{exposedPod},
+				services:   []corev1.Service{exposing("exposing"), exposing("unexposing")},
+			},
+			want: want{
+				deleted: []string{"unexposing"},
+			},
+		},
+		"DeleteOrphanedMirroredServices": {
+			reason: "Remote services that mirror a local service that no longer exists should be deleted",
+			objects: objects{
+				localPods:  []corev1.Pod{localPod},
+				localSvcs:  map[string]bool{"mirrored": true},
+				namespaces: []corev1.Namespace{ns},
+				services:   []corev1.Service{mirrored("mirrored"), mirrored("orphaned")},
+			},
+			want: want{
+				deleted: []string{"orphaned"},
+			},
+		},
+		"KeepClasses": {
+			reason: "Remote classes should not be deleted unless configured to collect them",
+			objects: objects{
+				localPods:  []corev1.Pod{localPod},
+				namespaces: []corev1.Namespace{ns},
+				priorities: []schedulingv1.PriorityClass{{ObjectMeta: classMeta("unused")}},
+				runtimes:   []nodev1beta1.RuntimeClass{{ObjectMeta: classMeta("unused")}},
+			},
+		},
+		"DeleteUnusedClasses": {
+			reason: "Replicated remote classes that no local pod uses should be deleted",
+			objects: objects{
+				localPods:  []corev1.Pod{classPod},
+				namespaces: []corev1.Namespace{ns},
+				priorities: []schedulingv1.PriorityClass{{ObjectMeta: classMeta("used")}, {ObjectMeta: classMeta("unused")}},
+				runtimes:   []nodev1beta1.RuntimeClass{{ObjectMeta: classMeta("used")}, {ObjectMeta: classMeta("unused")}},
+			},
+			o: []GarbageCollectorOption{WithClassCollection(true, true)},
+			want: want{
+				deleted: []string{remote.ClassName(nodeName, "unused"), remote.ClassName(nodeName, "unused")},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			deleted := make([]string, 0)
+
+			lcl := &test.MockClient{
+				MockList: func(_ context.Context, obj runtime.Object, _ ...client.ListOption) error {
+					if l, ok := obj.(*corev1.PodList); ok {
+						l.Items = tc.objects.localPods
+					}
+					return tc.listErr
+				},
+				MockGet: func(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+					switch obj.(type) {
+					case *corev1.PersistentVolumeClaim:
+						if tc.objects.localClaims[key.Name] {
+							return nil
+						}
+					case *corev1.Service:
+						if tc.objects.localSvcs[key.Name] {
+							return nil
+						}
+					}
+					return kerrors.NewNotFound(schema.GroupResource{}, key.Name)
+				},
+			}
+			rmt := &test.MockClient{
+				MockList: func(_ context.Context, obj runtime.Object, _ ...client.ListOption) error {
+					switch l := obj.(type) {
+					case *corev1.NamespaceList:
+						l.Items = tc.objects.namespaces
+					case *corev1.PodList:
+						l.Items = tc.objects.remotePods
+					case *corev1.ConfigMapList:
+						l.Items = tc.objects.configMaps
+					case *corev1.SecretList:
+						l.Items = tc.objects.secrets
+					case *corev1.PersistentVolumeClaimList:
+						l.Items = tc.objects.claims
+					case *corev1.ServiceList:
+						l.Items = tc.objects.services
+					case *schedulingv1.PriorityClassList:
+						l.Items = tc.objects.priorities
+					case *nodev1beta1.RuntimeClassList:
+						l.Items = tc.objects.runtimes
+					}
+					return nil
+				},
+				MockDelete: func(_ context.Context, obj runtime.Object, _ ...client.DeleteOption) error {
+					deleted = append(deleted, obj.(metav1.Object).GetName())
+					return nil
+				},
+			}
+
+			gc := NewGarbageCollector(lcl, rmt, nodeName, tc.o...)
+			err := gc.Collect(context.Background())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ngc.Collect(...): -want error, +got error: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.deleted, deleted, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\ngc.Collect(...): -want deleted, +got deleted: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
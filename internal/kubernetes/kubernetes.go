@@ -2,39 +2,95 @@ package kubernetes
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/virtual-kubelet/node-cli/provider"
 	"github.com/virtual-kubelet/virtual-kubelet/errdefs"
 	"github.com/virtual-kubelet/virtual-kubelet/log"
 	"github.com/virtual-kubelet/virtual-kubelet/node/api"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/propagators"
 	corev1 "k8s.io/api/core/v1"
+	nodev1beta1 "k8s.io/api/node/v1beta1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
+	kresource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/deprecated/scheme"
 	kcache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
 	"github.com/negz/actual-kubelets/internal/pointer"
 	"github.com/negz/actual-kubelets/internal/remote"
 )
 
-// A Provider runs pods by submitting them to a remote API server.
+const (
+	// The name calls to the local API server are attributed to.
+	localClusterName = "local"
+
+	defaultNodeRefreshInterval = 1 * time.Minute
+	defaultProbeInterval       = 10 * time.Second
+	defaultFailureThreshold    = 3
+)
+
+// Reasons for events recorded on local pods.
+const (
+	ReasonDependencyMissing       = "DependencyMissing"
+	ReasonRemoteNamespaceCreated  = "RemoteNamespaceCreated"
+	ReasonRemoteCreateFailed      = "RemoteCreateFailed"
+	ReasonRemoteUpdateRejected    = "RemoteUpdateRejected"
+	ReasonRemoteExposeFailed      = "RemoteExposeFailed"
+	ReasonRemotePodEvicted        = "RemotePodEvicted"
+	ReasonRemoteAdmissionRejected = "RemoteAdmissionRejected"
+)
+
+// The reason the kubelet reports for pods it has evicted.
+const reasonEvicted = "Evicted"
+
+// A Provider runs pods by submitting them to one or more remote API servers.
 type Provider struct {
 	dependencies DependencyFetcher
-	remote       Client
+	local        Client
+	remotes      []RemoteCluster
+	placer       Placer
+	namer        remote.NamespaceNamer
+	exposer      *PodExposer
+	mutator      *PodMutator
+	quotas       *NamespaceQuotaApplicator
+	failed       *FailedPods
+	metrics      *Metrics
+	recorder     record.EventRecorder
 	nodeName     string
 	cfg          Config
+
+	// The AK node, as last configured by ConfigureNode.
+	mu   sync.RWMutex
+	node *corev1.Node
 }
 
 // NewProvider returns a Provider that runs pods by submitting them to a remote
-// API server.
-func NewProvider(ic provider.InitConfig) (provider.Provider, error) {
+// API server. Any background processes the Provider starts will run until the
+// supplied context is done.
+func NewProvider(ctx context.Context, ic provider.InitConfig) (*Provider, error) {
 	if ic.ConfigPath == "" {
 		return nil, errors.New("provider config file is required")
 	}
@@ -44,19 +100,66 @@ func NewProvider(ic provider.InitConfig) (provider.Provider, error) {
 		return nil, errors.Wrap(err, "cannot parse provider config")
 	}
 
-	local, err := NewClient(cfg.Local)
+	local, err := NewClient(localClusterName, cfg.Local)
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot create client for local (kubelet) API server")
 	}
 
-	remote, err := NewClient(cfg.Remote)
+	rcs := make([]RemoteCluster, 0, len(cfg.RemoteConfigs()))
+	for _, rc := range cfg.RemoteConfigs() {
+		c, err := NewClient(rc.Name, rc.ClientConfig)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot create client for remote (backing) API server %q", rc.Name)
+		}
+		rcs = append(rcs, RemoteCluster{Name: rc.Name, Client: c})
+	}
+
+	namer, err := NewNamespaceNamer(cfg.Namespaces)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create namespace namer")
+	}
+
+	mutator, err := NewPodMutator(cfg.Pods.Mutations)
 	if err != nil {
-		return nil, errors.Wrap(err, "cannot create client for remote (backing) API server")
+		return nil, errors.Wrap(err, "cannot create pod mutator")
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewGoCollector(), prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+	m, err := NewMetrics(reg)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create metrics")
+	}
+
+	if cfg.Tracing.Endpoint != "" {
+		tp, err := NewTracerProvider(cfg.Tracing)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot create tracer provider")
+		}
+		global.SetTracerProvider(tp)
+		global.SetTextMapPropagator(propagators.TraceContext{})
+		go func() {
+			<-ctx.Done()
+			_ = tp.Shutdown(context.Background())
+		}()
 	}
 
+	deps := NewAPIDependencyFetcher(local,
+		WithTokenIssuer(APITokenIssuer(local)),
+		WithStorageClasses(cfg.Storage.Classes),
+		WithClassReplication(cfg.Pods.PriorityClasses.Mode == ClassModeReplicate, cfg.Pods.RuntimeClasses.Mode == ClassModeReplicate))
+
 	p := &Provider{
-		dependencies: NewAPIDependencyFetcher(local),
-		remote:       remote,
+		dependencies: deps,
+		local:        local,
+		remotes:      rcs,
+		placer:       NewPlacer(cfg.Placement, ic.NodeName),
+		namer:        namer,
+		mutator:      mutator,
+		quotas:       NewNamespaceQuotaApplicator(cfg.Namespaces, ic.NodeName, namer),
+		failed:       NewFailedPods(),
+		metrics:      m,
+		recorder:     NewEventRecorder(ctx, local, ic.NodeName),
 		nodeName:     ic.NodeName,
 		cfg: Config{
 			InitConfig: ic,
@@ -64,20 +167,115 @@ func NewProvider(ic provider.InitConfig) (provider.Provider, error) {
 		},
 	}
 
+	go p.refreshMetrics(ctx)
+	go p.quotas.Run(ctx, rcs)
+
+	if cfg.Pods.Expose.Type != "" {
+		p.exposer = NewPodExposer(cfg.Pods.Expose)
+	}
+
+	as := make([]resource.Applicator, len(rcs))
+	for i := range rcs {
+		as[i] = rcs[i]
+	}
+	if err := NewDependencySyncer(local, as, ic.NodeName, WithNamespaceNamer(namer)).Start(ctx, local); err != nil {
+		return nil, errors.Wrap(err, "cannot start pod dependency syncer")
+	}
+
+	go NewTokenRotator(local, APITokenIssuer(local), rcs, ic.NodeName, namer).Run(ctx)
+
+	if sc := cfg.Services; sc.Mirror != "" {
+		cas := make([]resource.ClientApplicator, len(rcs))
+		for i := range rcs {
+			cas[i] = rcs[i].ClientApplicator
+		}
+		sm := NewServiceMirror(local, cas, ic.NodeName, WithMirrorNamespaceNamer(namer), WithMirrorMode(sc.Mirror, sc.Domain))
+		if err := sm.Start(ctx, local); err != nil {
+			return nil, errors.Wrap(err, "cannot start service mirror")
+		}
+	}
+
+	cs := NewClaimStatusSyncer(local, ic.NodeName)
+	for _, rc := range rcs {
+		if err := cs.Start(ctx, rc); err != nil {
+			return nil, errors.Wrapf(err, "cannot start persistent volume claim status syncer for remote cluster %q", rc.Name)
+		}
+	}
+
+	if ec := cfg.Events; ec.Mirror {
+		o := []EventMirrorOption{}
+		if ec.QPS > 0 && ec.Burst > 0 {
+			o = append(o, WithEventRateLimit(ec.QPS, ec.Burst))
+		}
+		for _, rc := range rcs {
+			if err := NewEventMirror(local, rc, p.recorder, ic.NodeName, o...).Start(ctx, rc); err != nil {
+				return nil, errors.Wrapf(err, "cannot start event mirror for remote cluster %q", rc.Name)
+			}
+		}
+	}
+
+	if gcc := cfg.GarbageCollection; gcc.Interval.Duration > 0 {
+		o := []GarbageCollectorOption{
+			WithCollectionInterval(gcc.Interval.Duration),
+			WithDryRun(gcc.DryRun),
+			WithClassCollection(cfg.Pods.PriorityClasses.Mode == ClassModeReplicate, cfg.Pods.RuntimeClasses.Mode == ClassModeReplicate),
+		}
+		if gcc.GracePeriod.Duration > 0 {
+			o = append(o, WithGracePeriod(gcc.GracePeriod.Duration))
+		}
+		// NOTE(negz): Each remote cluster's garbage collector considers the
+		// dependencies of all local pods scheduled to our node, regardless
+		// of which remote cluster they run in. This means a namespace may
+		// linger in one remote cluster as long as its local namespace has pods
+		// running in another.
+		for _, rc := range rcs {
+			go NewGarbageCollector(local, rc, ic.NodeName, o...).Run(ctx)
+		}
+	}
+
+	// Only serve metrics once we know the provider can be created; the metrics
+	// server is shut down when the supplied context is done.
+	if cfg.Metrics.Port > 0 {
+		go ServeMetrics(ctx, cfg.Metrics.Port, reg)
+	}
+
 	return p, nil
 }
 
 // ApplyPodDependencies applies (i.e. creates or overwrites) the resources the
-// supplied pod depends on in order to work as expected.
-func (p *Provider) ApplyPodDependencies(ctx context.Context, lcl *corev1.Pod) error {
+// supplied pod depends on in order to work as expected to the supplied remote
+// cluster.
+func (p *Provider) ApplyPodDependencies(ctx context.Context, rc RemoteCluster, lcl *corev1.Pod) (err error) {
+	defer p.metrics.ObserveOperation(OperationApplyPodDependencies, time.Now(), &err)
+	ctx, span := startSpan(ctx, "Provider.ApplyPodDependencies", podAttributes(lcl.GetNamespace(), lcl.GetName(), attrRemote.String(rc.Name))...)
+	defer endSpan(span, &err)
+
 	deps, err := p.dependencies.Fetch(ctx, lcl)
+	if kerrors.IsNotFound(errors.Cause(err)) {
+		p.recorder.Eventf(lcl, corev1.EventTypeWarning, ReasonDependencyMissing, "Cannot find pod dependency: %s", errors.Cause(err))
+	}
 	if err != nil {
 		return errors.Wrap(err, "cannot fetch local pod dependencies")
 	}
 
-	ns := remote.Namespace(p.nodeName, lcl.GetNamespace())
-	if err := p.remote.Apply(ctx, ns); err != nil {
-		return errors.Wrap(err, "cannot apply remote pod namespace")
+	ns := remote.Namespace(p.nodeName, lcl.GetNamespace(), remote.WithNamespaceNamer(p.namer))
+	err = rc.Get(ctx, types.NamespacedName{Name: ns.GetName()}, &corev1.Namespace{})
+	switch {
+	case kerrors.IsNotFound(err):
+		if err := rc.Create(ctx, ns); err != nil && !kerrors.IsAlreadyExists(err) {
+			return errors.Wrap(err, "cannot create remote pod namespace")
+		}
+		p.recorder.Eventf(lcl, corev1.EventTypeNormal, ReasonRemoteNamespaceCreated, "Created namespace %s in remote cluster %s", ns.GetName(), rc.Name)
+	case err != nil:
+		return errors.Wrap(err, "cannot get remote pod namespace")
+	default:
+		if err := rc.Apply(ctx, ns); err != nil {
+			return errors.Wrap(err, "cannot apply remote pod namespace")
+		}
+	}
+
+	if err := p.quotas.Apply(ctx, rc.ClientApplicator, lcl.GetNamespace()); err != nil {
+		return errors.Wrap(err, "cannot apply remote pod namespace quota")
 	}
 
 	// NOTE(negz): Multiple pods might share the same dependency within a
@@ -85,8 +283,28 @@ func (p *Provider) ApplyPodDependencies(ctx context.Context, lcl *corev1.Pod) er
 	// them all for every pod, so applying pod A might also apply dependencies
 	// of pod B.
 	for _, d := range deps {
-		remote.PrepareObject(p.nodeName, d)
-		if err := p.remote.Apply(ctx, d); err != nil {
+		// TODO(negz): Garbage collect replicated classes once no pod scheduled
+		// to this node references them.
+		switch o := d.(type) {
+		case *schedulingv1.PriorityClass:
+			remote.PreparePriorityClass(p.nodeName, o)
+		case *nodev1beta1.RuntimeClass:
+			remote.PrepareRuntimeClass(p.nodeName, o)
+		default:
+			remote.PrepareObject(p.nodeName, d, remote.WithNamespaceNamer(p.namer))
+		}
+
+		// Persistent volume claims are bound by the remote cluster, and their
+		// specs are mostly immutable once created. We create them if they
+		// don't exist, but never update them.
+		if _, ok := d.(*corev1.PersistentVolumeClaim); ok {
+			if err := rc.Create(ctx, d); err != nil && !kerrors.IsAlreadyExists(err) {
+				return errors.Wrap(err, "cannot create remote persistent volume claim")
+			}
+			continue
+		}
+
+		if err := rc.Apply(ctx, d); err != nil {
 			return errors.Wrap(err, "cannot apply remote pod dependency")
 		}
 	}
@@ -94,43 +312,172 @@ func (p *Provider) ApplyPodDependencies(ctx context.Context, lcl *corev1.Pod) er
 	return nil
 }
 
-// CreatePod prepares the supplied pod and creates it in the remote API server.
-func (p *Provider) CreatePod(ctx context.Context, lcl *corev1.Pod) error {
-	if err := p.ApplyPodDependencies(ctx, lcl); err != nil {
+// CreatePod prepares the supplied pod and creates it in a remote API server.
+func (p *Provider) CreatePod(ctx context.Context, lcl *corev1.Pod) (err error) {
+	defer p.metrics.ObserveOperation(OperationCreatePod, time.Now(), &err)
+	ctx, span := startSpan(ctx, "Provider.CreatePod", podAttributes(lcl.GetNamespace(), lcl.GetName())...)
+	defer endSpan(span, &err)
+
+	rc, err := p.place(ctx, lcl)
+	if err != nil {
+		return err
+	}
+	span.SetAttributes(attrRemote.String(rc.Name))
+
+	if err := p.ApplyPodDependencies(ctx, rc, lcl); err != nil {
 		return errors.Wrap(err, "cannot apply remote pod dependencies")
 	}
 
+	ppo := []remote.PreparePodOption{
+		remote.WithEnvVars(p.cfg.Pods.Env...),
+		remote.WithObjectOptions(remote.WithNamespaceNamer(p.namer)),
+	}
+	if sc := p.cfg.Pods.Scheduling; sc.Mode == SchedulingModeTranslate {
+		ppo = append(ppo, remote.WithSchedulingTranslation(sc.NodeLabelKeys...))
+	}
+
 	rmt := lcl.DeepCopy()
-	remote.PreparePod(p.nodeName, rmt, remote.WithEnvVars(p.cfg.Pods.Env...))
-	err := p.remote.Create(ctx, rmt)
-	return errors.Wrap(err, "cannot apply remote pod")
+	remote.PreparePod(p.nodeName, rmt, ppo...)
+	if n := rmt.Spec.PriorityClassName; n != "" {
+		rmt.Spec.PriorityClassName = p.cfg.Pods.PriorityClasses.RemoteName(p.nodeName, n)
+	}
+	if n := pointer.DerefStringOr(rmt.Spec.RuntimeClassName, ""); n != "" {
+		rmt.Spec.RuntimeClassName = pointer.String(p.cfg.Pods.RuntimeClasses.RemoteName(p.nodeName, n))
+	}
+	if err := p.mutator.Mutate(lcl, rmt); err != nil {
+		p.recorder.Eventf(lcl, corev1.EventTypeWarning, ReasonRemoteCreateFailed, "Cannot mutate pod: %s", err)
+		return errors.Wrap(err, "cannot mutate remote pod")
+	}
+	if p.exposer != nil {
+		p.exposer.PreparePod(rmt)
+	}
+	if tc := TraceContext(ctx); tc != "" {
+		meta.AddAnnotations(rmt, map[string]string{remote.AnnotationKeyTraceContext: tc})
+	}
+
+	// ApplyPodDependencies has already confirmed that all of the pod's
+	// required dependencies exist. A pod the remote cluster would reject will
+	// never succeed, so we fail it rather than retrying. The failure is
+	// recorded in the local API server so that it outlives this process.
+	if err := Preflight(ctx, rc, rmt); err != nil {
+		msg := fmt.Sprintf("Remote cluster %s would reject pod: %s", rc.Name, err)
+		p.recorder.Event(lcl, corev1.EventTypeWarning, ReasonRemoteAdmissionRejected, msg)
+		failed := p.failed.Fail(lcl, ReasonRemoteAdmissionRejected, msg)
+		return errors.Wrap(p.local.Status().Update(ctx, failed), "cannot update local pod status")
+	}
+
+	if err := rc.Create(ctx, rmt); err != nil {
+		p.recorder.Eventf(lcl, corev1.EventTypeWarning, ReasonRemoteCreateFailed, "Cannot create pod in remote cluster %s: %s", rc.Name, err)
+		return errors.Wrap(err, "cannot apply remote pod")
+	}
+
+	// The remote pod is running, even if we can't expose it. We surface the
+	// problem on the local pod rather than failing to create it.
+	if p.exposer != nil {
+		if err := p.exposer.Expose(ctx, rc, rmt); err != nil {
+			p.recorder.Eventf(lcl, corev1.EventTypeWarning, ReasonRemoteExposeFailed, "Cannot expose remote pod: %s", err)
+		}
+	}
+	return nil
+}
+
+// place returns the remote cluster the supplied pod should be created in. A pod
+// that already exists in a remote cluster, for example because a previous
+// attempt to create it partially succeeded, is kept where it is.
+func (p *Provider) place(ctx context.Context, lcl *corev1.Pod) (RemoteCluster, error) {
+	rc, _, err := p.getRemotePod(ctx, lcl.GetNamespace(), lcl.GetName())
+	if err == nil {
+		return rc, nil
+	}
+	if !errdefs.IsNotFound(err) {
+		return RemoteCluster{}, err
+	}
+
+	rc, err = p.placer.Place(ctx, lcl, p.remotes)
+	return rc, errors.Wrap(err, "cannot place pod in a remote cluster")
+}
+
+// getRemotePod returns the remote pod corresponding to the supplied local
+// namespace and name, and the remote cluster it is running in.
+func (p *Provider) getRemotePod(ctx context.Context, namespace, name string) (RemoteCluster, *corev1.Pod, error) {
+	nn := types.NamespacedName{Namespace: p.namer.NamespaceName(p.nodeName, namespace), Name: name}
+	for _, rc := range p.remotes {
+		rmt := &corev1.Pod{}
+		err := rc.Get(ctx, nn, rmt)
+		if kerrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return RemoteCluster{}, nil, errors.Wrapf(err, "cannot get pod from remote cluster %q", rc.Name)
+		}
+		return rc, rmt, nil
+	}
+	return RemoteCluster{}, nil, errdefs.NotFoundf("pod %s/%s not found in any remote cluster", namespace, name)
 }
 
 // UpdatePod prepares the supplied pod and updates it in the remote API server.
-func (p *Provider) UpdatePod(ctx context.Context, lcl *corev1.Pod) error {
-	if err := p.ApplyPodDependencies(ctx, lcl); err != nil {
+func (p *Provider) UpdatePod(ctx context.Context, lcl *corev1.Pod) (err error) {
+	defer p.metrics.ObserveOperation(OperationUpdatePod, time.Now(), &err)
+	ctx, span := startSpan(ctx, "Provider.UpdatePod", podAttributes(lcl.GetNamespace(), lcl.GetName())...)
+	defer endSpan(span, &err)
+
+	rc, rmt, err := p.getRemotePod(ctx, lcl.GetNamespace(), lcl.GetName())
+	if err != nil {
+		return errors.Wrap(err, "cannot get remote pod")
+	}
+
+	if err := p.ApplyPodDependencies(ctx, rc, lcl); err != nil {
 		return errors.Wrap(err, "cannot apply remote pod dependencies")
 	}
 
-	rmt := &corev1.Pod{}
-	nn := types.NamespacedName{Namespace: remote.NamespaceName(p.nodeName, lcl.GetNamespace()), Name: lcl.GetName()}
-	if err := p.remote.Get(ctx, nn, rmt); err != nil {
-		return errors.Wrap(err, "cannot get remote pod")
+	// Ephemeral containers can only be added via the ephemeralcontainers
+	// subresource, so we compute them before the update changes the remote
+	// pod's resource version.
+	ecs := remote.PrepareEphemeralContainers(lcl, rmt)
+
+	remote.PreparePodUpdate(p.nodeName, lcl, rmt, remote.WithNamespaceNamer(p.namer))
+	p.mutator.MutateMetadata(lcl, rmt)
+	err = rc.Update(ctx, rmt)
+
+	// The remote API server may refuse an update that the local API server
+	// accepted, for example because a remote admission webhook rejects it.
+	// Retrying won't help, so we surface the problem on the local pod.
+	if kerrors.IsInvalid(err) || kerrors.IsForbidden(err) {
+		p.recorder.Eventf(lcl, corev1.EventTypeWarning, ReasonRemoteUpdateRejected, "Cannot apply local pod update to remote pod: %s", err)
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "cannot update remote pod")
 	}
 
-	remote.PreparePodUpdate(p.nodeName, lcl, rmt)
-	err := p.remote.Update(ctx, rmt)
-	return errors.Wrap(err, "cannot update remote pod")
+	if ecs == nil {
+		return nil
+	}
+	ecs.SetResourceVersion(rmt.GetResourceVersion())
+	_, err = rc.CoreV1().Pods(rmt.GetNamespace()).UpdateEphemeralContainers(ctx, rmt.GetName(), ecs, metav1.UpdateOptions{})
+	if kerrors.IsInvalid(err) || kerrors.IsForbidden(err) || kerrors.IsNotFound(err) {
+		p.recorder.Eventf(lcl, corev1.EventTypeWarning, ReasonRemoteUpdateRejected, "Cannot add ephemeral containers to remote pod: %s", err)
+		return nil
+	}
+	return errors.Wrap(err, "cannot update remote pod ephemeral containers")
 }
 
 // DeletePod from the remote API server.
-func (p *Provider) DeletePod(ctx context.Context, lcl *corev1.Pod) error {
-	// TODO(negz): Garbage collect empty namespaces and orphaned dependencies?
-	// This could potentially be better left to a garbage collection controller
-	// in the remote cluster.
-	rmt := lcl.DeepCopy()
-	remote.PreparePod(p.nodeName, rmt)
-	err := p.remote.Delete(ctx, rmt)
+func (p *Provider) DeletePod(ctx context.Context, lcl *corev1.Pod) (err error) {
+	defer p.metrics.ObserveOperation(OperationDeletePod, time.Now(), &err)
+	ctx, span := startSpan(ctx, "Provider.DeletePod", podAttributes(lcl.GetNamespace(), lcl.GetName())...)
+	defer endSpan(span, &err)
+
+	p.failed.Forget(lcl.GetNamespace(), lcl.GetName())
+
+	// NOTE(negz): We don't delete the remote namespace or any dependencies
+	// here, because other pods may still be using them. The GarbageCollector
+	// cleans them up once they're no longer needed.
+	rc, rmt, err := p.getRemotePod(ctx, lcl.GetNamespace(), lcl.GetName())
+	if err != nil {
+		return err
+	}
+	err = rc.Delete(ctx, rmt)
 	if kerrors.IsNotFound(err) {
 		return errdefs.AsNotFound(err)
 	}
@@ -138,83 +485,186 @@ func (p *Provider) DeletePod(ctx context.Context, lcl *corev1.Pod) error {
 }
 
 // GetPod retrieves a pod by name from the remote API server.
-func (p *Provider) GetPod(ctx context.Context, namespace, name string) (*corev1.Pod, error) {
-	rmt := &corev1.Pod{}
-	nn := types.NamespacedName{Namespace: remote.NamespaceName(p.nodeName, namespace), Name: name}
-	err := p.remote.Get(ctx, nn, rmt)
-	if kerrors.IsNotFound(err) {
-		return nil, errdefs.AsNotFound(err)
+func (p *Provider) GetPod(ctx context.Context, namespace, name string) (_ *corev1.Pod, err error) {
+	ctx, span := startSpan(ctx, "Provider.GetPod", podAttributes(namespace, name)...)
+	defer endSpan(span, &err)
+
+	if pod, ok := p.failed.Get(namespace, name); ok {
+		return pod, nil
 	}
-	remote.RecoverPod(rmt)
-	return rmt.DeepCopy(), errors.Wrap(err, "cannot get pod")
+
+	rc, rmt, err := p.getRemotePod(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	p.recoverPod(ctx, rc, rmt)
+	return rmt, nil
 }
 
 // GetPodStatus retrieves the status of a pod by name from the remote API
 // server.
-func (p *Provider) GetPodStatus(ctx context.Context, namespace, name string) (*corev1.PodStatus, error) {
-	rmt := &corev1.Pod{}
-	nn := types.NamespacedName{Namespace: remote.NamespaceName(p.nodeName, namespace), Name: name}
-	err := p.remote.Get(ctx, nn, rmt)
-	if kerrors.IsNotFound(err) {
-		return nil, errdefs.AsNotFound(err)
+func (p *Provider) GetPodStatus(ctx context.Context, namespace, name string) (_ *corev1.PodStatus, err error) {
+	ctx, span := startSpan(ctx, "Provider.GetPodStatus", podAttributes(namespace, name)...)
+	defer endSpan(span, &err)
+
+	if pod, ok := p.failed.Get(namespace, name); ok {
+		return &pod.Status, nil
 	}
-	remote.RecoverPod(rmt)
-	return rmt.Status.DeepCopy(), errors.Wrap(err, "cannot get pod")
+
+	rc, rmt, err := p.getRemotePod(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	p.recoverPod(ctx, rc, rmt)
+	return &rmt.Status, nil
 }
 
-// GetPods retrieves a list of all pods running on the remote API server.
-func (p *Provider) GetPods(ctx context.Context) ([]*corev1.Pod, error) {
-	l := &corev1.PodList{}
-	if err := p.remote.List(ctx, l, client.HasLabels([]string{remote.LabelKeyNodeName})); err != nil {
-		return nil, errors.Wrap(err, "cannot list pods")
+// recoverPod recovers the supplied remote pod for representation in the local
+// cluster, reporting the address at which it is exposed as its pod IP.
+func (p *Provider) recoverPod(ctx context.Context, rc RemoteCluster, pod *corev1.Pod) {
+	if p.exposer != nil {
+		if err := p.exposer.RecoverPodIP(ctx, rc, pod); err != nil {
+			log.G(ctx).WithError(err).WithField("remote", rc.Name).Debug("cannot recover exposed pod IP")
+		}
 	}
+	remote.RecoverPod(pod)
+}
+
+// GetPods retrieves a list of all pods running on all remote API servers.
+func (p *Provider) GetPods(ctx context.Context) (_ []*corev1.Pod, err error) {
+	ctx, span := startSpan(ctx, "Provider.GetPods")
+	defer endSpan(span, &err)
 
-	pods := make([]*corev1.Pod, len(l.Items))
-	for i := range l.Items {
-		pod := l.Items[i].DeepCopy()
-		remote.RecoverPod(pod)
-		pods[i] = pod
+	pods := make([]*corev1.Pod, 0)
+	for _, rc := range p.remotes {
+		l := &corev1.PodList{}
+		if err := rc.List(ctx, l, client.HasLabels([]string{remote.LabelKeyNodeName})); err != nil {
+			return nil, errors.Wrapf(err, "cannot list pods in remote cluster %q", rc.Name)
+		}
+
+		for i := range l.Items {
+			pod := l.Items[i].DeepCopy()
+			p.recoverPod(ctx, rc, pod)
+			pods = append(pods, pod)
+		}
 	}
 
-	return pods, nil
+	return append(pods, p.failed.List()...), nil
 }
 
-// NotifyPods calls the supplied changed function when a pod in the remote API
-// server may have changed.
+// NotifyPods calls the supplied changed function when a pod in any remote API
+// server may have changed, or when a pod fails its preflight checks.
 func (p *Provider) NotifyPods(ctx context.Context, changed func(*corev1.Pod)) {
-	i, err := p.remote.GetInformer(ctx, &corev1.Pod{})
-	if err != nil {
-		log.G(ctx).Error("cannot get informer", err)
-		return
+	p.failed.Notify(changed)
+	for _, rc := range p.remotes {
+		i, err := rc.GetInformer(ctx, &corev1.Pod{})
+		if err != nil {
+			log.G(ctx).WithField("remote", rc.Name).Error("cannot get informer", err)
+			continue
+		}
+		i.AddEventHandler(p.notifyPodHandler(ctx, rc, changed))
 	}
-	i.AddEventHandler(kcache.ResourceEventHandlerFuncs{
+}
+
+func (p *Provider) notifyPodHandler(ctx context.Context, rc RemoteCluster, changed func(*corev1.Pod)) kcache.ResourceEventHandler {
+	return kcache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			if rmt, ok := obj.(*corev1.Pod); ok {
+				p.metrics.ObserveInformerLag(rc.Name, rmt)
 				lcl := rmt.DeepCopy()
-				remote.RecoverPod(lcl)
+				p.recoverPod(ctx, rc, lcl)
 				changed(lcl)
 			}
 		},
-		UpdateFunc: func(_, obj interface{}) {
+		UpdateFunc: func(oldObj, obj interface{}) {
 			if rmt, ok := obj.(*corev1.Pod); ok {
+				p.metrics.ObserveInformerLag(rc.Name, rmt)
+				if old, ok := oldObj.(*corev1.Pod); ok && !evicted(old) && evicted(rmt) {
+					p.recordEviction(ctx, rc, rmt)
+				}
 				lcl := rmt.DeepCopy()
-				remote.RecoverPod(lcl)
+				p.recoverPod(ctx, rc, lcl)
 				changed(lcl)
 			}
 		},
 		DeleteFunc: func(obj interface{}) {
 			if rmt, ok := obj.(*corev1.Pod); ok {
 				lcl := rmt.DeepCopy()
-				remote.RecoverPod(lcl)
+				p.recoverPod(ctx, rc, lcl)
 				changed(lcl)
 			}
 		},
-	})
+	}
+}
+
+func evicted(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodFailed && pod.Status.Reason == reasonEvicted
+}
+
+// recordEviction records an event on the local pod corresponding to the
+// supplied remote pod, which has been evicted by its remote kubelet.
+func (p *Provider) recordEviction(ctx context.Context, rc RemoteCluster, rmt *corev1.Pod) {
+	if rmt.GetLabels()[remote.LabelKeyNodeName] != p.nodeName {
+		return
+	}
+	lcl := &corev1.Pod{}
+	nn := types.NamespacedName{Namespace: rmt.GetLabels()[remote.LabelKeyNamespace], Name: rmt.GetName()}
+	if err := p.local.Get(ctx, nn, lcl); err != nil {
+		log.G(ctx).WithError(err).WithField("remote", rc.Name).Debug("cannot get evicted local pod")
+		return
+	}
+	p.recorder.Eventf(lcl, corev1.EventTypeWarning, ReasonRemotePodEvicted, "Pod was evicted from remote cluster %s: %s", rc.Name, rmt.Status.Message)
+}
+
+// GetStatsSummary returns statistics for all pods running in remote clusters
+// on behalf of our node, as reported by the kubelets of the remote nodes they
+// run on.
+func (p *Provider) GetStatsSummary(ctx context.Context) (_ *stats.Summary, err error) {
+	ctx, span := startSpan(ctx, "Provider.GetStatsSummary")
+	defer endSpan(span, &err)
+
+	ps := make([]stats.PodStats, 0)
+	for _, rc := range p.remotes {
+		l := &corev1.PodList{}
+		if err := rc.List(ctx, l, client.MatchingLabels{remote.LabelKeyNodeName: p.nodeName}); err != nil {
+			return nil, errors.Wrapf(err, "cannot list pods in remote cluster %q", rc.Name)
+		}
+
+		nodes := map[string][]corev1.Pod{}
+		for _, pod := range l.Items {
+			if pod.Spec.NodeName == "" {
+				continue
+			}
+			nodes[pod.Spec.NodeName] = append(nodes[pod.Spec.NodeName], pod)
+		}
+
+		for node, pods := range nodes {
+			// We don't want to fail to report any stats just because we can't
+			// reach one remote node.
+			lg := log.G(ctx).WithField("remote", rc.Name).WithField("node", node)
+			raw, err := rc.CoreV1().RESTClient().Get().Resource("nodes").Name(node).SubResource("proxy").Suffix("stats/summary").Do(ctx).Raw()
+			if err != nil {
+				lg.WithError(err).Debug("cannot get remote node stats summary")
+				continue
+			}
+			s := &stats.Summary{}
+			if err := json.Unmarshal(raw, s); err != nil {
+				lg.WithError(err).Debug("cannot unmarshal remote node stats summary")
+				continue
+			}
+			ps = append(ps, FilterPodStats(s, pods)...)
+		}
+	}
+
+	return &stats.Summary{Node: SummarizeNode(p.nodeName, ps), Pods: ps}, nil
 }
 
 // GetContainerLogs retrieves the logs of a container by name from the remote
 // API server
-func (p *Provider) GetContainerLogs(ctx context.Context, namespace, podName, containerName string, opts api.ContainerLogOpts) (io.ReadCloser, error) {
+func (p *Provider) GetContainerLogs(ctx context.Context, namespace, podName, containerName string, opts api.ContainerLogOpts) (_ io.ReadCloser, err error) {
+	ctx, span := startSpan(ctx, "Provider.GetContainerLogs", podAttributes(namespace, podName)...)
+	defer endSpan(span, &err)
+
 	o := &corev1.PodLogOptions{
 		Container:    containerName,
 		Timestamps:   opts.Timestamps,
@@ -231,7 +681,12 @@ func (p *Provider) GetContainerLogs(ctx context.Context, namespace, podName, con
 		}(),
 	}
 
-	logs := p.remote.CoreV1().Pods(remote.NamespaceName(p.nodeName, namespace)).GetLogs(podName, o)
+	rc, _, err := p.getRemotePod(ctx, namespace, podName)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := rc.CoreV1().Pods(p.namer.NamespaceName(p.nodeName, namespace)).GetLogs(podName, o)
 	r, err := logs.Stream(ctx)
 	return r, errors.Wrap(err, "cannot stream container logs")
 }
@@ -239,7 +694,41 @@ func (p *Provider) GetContainerLogs(ctx context.Context, namespace, podName, con
 // RunInContainer executes a command in a container in the pod on the remote API
 // server, copying data between in/out/err and the container's
 // stdin/stdout/stderr.
-func (p *Provider) RunInContainer(ctx context.Context, namespace, podName, containerName string, cmd []string, attach api.AttachIO) error {
+func (p *Provider) RunInContainer(ctx context.Context, namespace, podName, containerName string, cmd []string, attach api.AttachIO) (err error) {
+	ctx, span := startSpan(ctx, "Provider.RunInContainer", podAttributes(namespace, podName)...)
+	defer endSpan(span, &err)
+
+	peo := &corev1.PodExecOptions{
+		Container: containerName,
+		Command:   cmd,
+		Stdin:     attach.Stdin() != nil,
+		Stdout:    attach.Stdout() != nil,
+		Stderr:    attach.Stderr() != nil,
+		TTY:       attach.TTY(),
+	}
+	return p.stream(ctx, namespace, podName, "exec", peo, attach)
+}
+
+// AttachToContainer attaches to a running container in the pod on the remote
+// API server, copying data between in/out/err and the container's
+// stdin/stdout/stderr. It's served by NewPodHandler.
+func (p *Provider) AttachToContainer(ctx context.Context, namespace, podName, containerName string, attach api.AttachIO) (err error) {
+	ctx, span := startSpan(ctx, "Provider.AttachToContainer", podAttributes(namespace, podName)...)
+	defer endSpan(span, &err)
+
+	pao := &corev1.PodAttachOptions{
+		Container: containerName,
+		Stdin:     attach.Stdin() != nil,
+		Stdout:    attach.Stdout() != nil,
+		Stderr:    attach.Stderr() != nil,
+		TTY:       attach.TTY(),
+	}
+	return p.stream(ctx, namespace, podName, "attach", pao, attach)
+}
+
+// stream data between in/out/err and the supplied streaming subresource (i.e.
+// exec or attach) of the pod on the remote API server.
+func (p *Provider) stream(ctx context.Context, namespace, podName, subresource string, o runtime.Object, attach api.AttachIO) error {
 	defer func() {
 		if attach.Stdout() != nil {
 			_ = attach.Stdout().Close()
@@ -249,25 +738,21 @@ func (p *Provider) RunInContainer(ctx context.Context, namespace, podName, conta
 		}
 	}()
 
-	peo := &corev1.PodExecOptions{
-		Container: containerName,
-		Command:   cmd,
-		Stdin:     attach.Stdin() != nil,
-		Stdout:    attach.Stdout() != nil,
-		Stderr:    attach.Stderr() != nil,
-		TTY:       attach.TTY(),
+	rc, _, err := p.getRemotePod(ctx, namespace, podName)
+	if err != nil {
+		return err
 	}
 
-	req := p.remote.CoreV1().RESTClient().
+	req := rc.CoreV1().RESTClient().
 		Post().
-		Namespace(remote.NamespaceName(p.nodeName, namespace)).
+		Namespace(p.namer.NamespaceName(p.nodeName, namespace)).
 		Resource(corev1.ResourcePods.String()).
 		Name(podName).
-		SubResource("exec").
+		SubResource(subresource).
 		Timeout(0).
-		VersionedParams(peo, scheme.ParameterCodec)
+		VersionedParams(o, scheme.ParameterCodec)
 
-	e, err := remotecommand.NewSPDYExecutor(p.remote.Config, http.MethodPost, req.URL())
+	e, err := remotecommand.NewSPDYExecutor(rc.Config, http.MethodPost, req.URL())
 	if err != nil {
 		return errors.Wrap(err, "cannot create remote command executor")
 	}
@@ -292,8 +777,103 @@ func (t *tsq) Next() *remotecommand.TerminalSize {
 	return &r
 }
 
+// PortForward forwards the supplied stream to the supplied port of the pod on
+// the remote API server. It's served by NewPodHandler.
+func (p *Provider) PortForward(ctx context.Context, namespace, podName string, port int32, stream io.ReadWriteCloser) (err error) {
+	ctx, span := startSpan(ctx, "Provider.PortForward", podAttributes(namespace, podName)...)
+	defer endSpan(span, &err)
+
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	rc, _, err := p.getRemotePod(ctx, namespace, podName)
+	if err != nil {
+		return err
+	}
+
+	req := rc.CoreV1().RESTClient().
+		Post().
+		Namespace(p.namer.NamespaceName(p.nodeName, namespace)).
+		Resource(corev1.ResourcePods.String()).
+		Name(podName).
+		SubResource("portforward")
+
+	t, u, err := spdy.RoundTripperFor(rc.Config)
+	if err != nil {
+		return errors.Wrap(err, "cannot create port forward round tripper")
+	}
+	conn, _, err := spdy.NewDialer(u, &http.Client{Transport: t}, http.MethodPost, req.URL()).Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		return errors.Wrap(err, "cannot dial remote port forward")
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// Each forwarded connection consists of an error stream and a data stream,
+	// correlated by a request ID. We only forward one connection, so we always
+	// use the same request ID.
+	h := http.Header{}
+	h.Set(corev1.StreamType, corev1.StreamTypeError)
+	h.Set(corev1.PortHeader, strconv.Itoa(int(port)))
+	h.Set(corev1.PortForwardRequestIDHeader, "0")
+	es, err := conn.CreateStream(h)
+	if err != nil {
+		return errors.Wrap(err, "cannot create remote port forward error stream")
+	}
+	// We never write to the error stream.
+	_ = es.Close()
+
+	h.Set(corev1.StreamType, corev1.StreamTypeData)
+	ds, err := conn.CreateStream(h)
+	if err != nil {
+		return errors.Wrap(err, "cannot create remote port forward data stream")
+	}
+
+	errs := make(chan error, 3)
+	go func() {
+		msg, err := ioutil.ReadAll(es)
+		switch {
+		case err != nil:
+			errs <- errors.Wrap(err, "cannot read remote port forward error stream")
+		case len(msg) > 0:
+			errs <- errors.Errorf("remote port forward error: %s", msg)
+		}
+	}()
+	go func() {
+		// Copy from the local stream to the remote data stream, then let the
+		// remote end know we're done writing.
+		if _, err := io.Copy(ds, stream); err != nil {
+			errs <- errors.Wrap(err, "cannot copy to remote port forward data stream")
+		}
+		_ = ds.Close()
+	}()
+
+	// We're done once the remote end is done writing to the data stream.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := io.Copy(stream, ds); err != nil {
+			errs <- errors.Wrap(err, "cannot copy from remote port forward data stream")
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-done:
+		return nil
+	case err := <-errs:
+		return err
+	}
+}
+
 // ConfigureNode configures the AK Node in the local API server.
-func (p *Provider) ConfigureNode(_ context.Context, n *corev1.Node) {
+func (p *Provider) ConfigureNode(ctx context.Context, n *corev1.Node) {
+	ctx, span := startSpan(ctx, "Provider.ConfigureNode")
+	defer span.End()
+
 	n.Status.NodeInfo.OperatingSystem = p.cfg.OperatingSystem
 
 	n.Status.Addresses = []corev1.NodeAddress{
@@ -304,21 +884,20 @@ func (p *Provider) ConfigureNode(_ context.Context, n *corev1.Node) {
 		KubeletEndpoint: corev1.DaemonEndpoint{Port: p.cfg.DaemonPort},
 	}
 
-	n.Status.Allocatable = make(corev1.ResourceList)
-	for name, quantity := range p.cfg.Node.Resources.Allocatable {
-		n.Status.Allocatable[corev1.ResourceName(name)] = resource.MustParse(quantity)
+	c, a, err := p.nodeResources(ctx)
+	if err != nil {
+		// Fall back to our static resources until they can be refreshed.
+		log.G(ctx).WithError(err).Error("cannot determine node resources")
+		c, a = p.staticResources()
 	}
+	n.Status.Capacity = c
+	n.Status.Allocatable = a
 
 	// TODO(negz): Would leaving these out impact anything?
 	n.Status.Conditions = []corev1.NodeCondition{
-		{
-			Type:               corev1.NodeReady,
-			Status:             corev1.ConditionTrue,
-			LastHeartbeatTime:  metav1.Now(),
-			LastTransitionTime: metav1.Now(),
-			Reason:             "KubeletReady",
-			Message:            "AK always reports that it is ready.",
-		},
+		// We assume the remote API server is reachable at startup, since we
+		// just connected to it. NotifyNodeStatus keeps this up to date.
+		ReadyCondition(nil),
 		{
 			Type:               corev1.NodeMemoryPressure,
 			Status:             corev1.ConditionFalse,
@@ -353,4 +932,195 @@ func (p *Provider) ConfigureNode(_ context.Context, n *corev1.Node) {
 			Message:            "AK always reports that RouteController created a route",
 		},
 	}
+
+	p.mu.Lock()
+	p.node = n.DeepCopy()
+	p.mu.Unlock()
+}
+
+// nodeResources returns the capacity and allocatable resources of the AK node.
+func (p *Provider) nodeResources(ctx context.Context) (corev1.ResourceList, corev1.ResourceList, error) {
+	if p.cfg.Node.Resources.Mode != NodeResourcesModeRemote {
+		c, a := p.staticResources()
+		return c, a, nil
+	}
+
+	capacity, allocatable := corev1.ResourceList{}, corev1.ResourceList{}
+	for _, rc := range p.remotes {
+		nl := &corev1.NodeList{}
+		if err := rc.List(ctx, nl); err != nil {
+			return nil, nil, errors.Wrapf(err, "cannot list nodes in remote cluster %q", rc.Name)
+		}
+		pl := &corev1.PodList{}
+		if err := rc.List(ctx, pl); err != nil {
+			return nil, nil, errors.Wrapf(err, "cannot list pods in remote cluster %q", rc.Name)
+		}
+
+		c, a := RemoteResources(p.nodeName, nl.Items, pl.Items)
+		addResources(capacity, c)
+		addResources(allocatable, a)
+	}
+
+	return capacity, allocatable, nil
+}
+
+// staticResources returns the capacity and allocatable resources specified by
+// the AK node's config file.
+func (p *Provider) staticResources() (corev1.ResourceList, corev1.ResourceList) {
+	a := make(corev1.ResourceList)
+	for name, quantity := range p.cfg.Node.Resources.Allocatable {
+		a[corev1.ResourceName(name)] = kresource.MustParse(quantity)
+	}
+	return a.DeepCopy(), a
+}
+
+// Ping the AK node. AK is considered to be alive as long as it is running; its
+// ability to reach the remote API server is instead reported via the NodeReady
+// condition, which is maintained by NotifyNodeStatus.
+func (p *Provider) Ping(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "Provider.Ping")
+	defer endSpan(span, &err)
+
+	return ctx.Err()
+}
+
+// NotifyNodeStatus calls the supplied changed function when the status of the
+// AK node may have changed. The node's NodeReady condition is updated when the
+// remote API server becomes (un)reachable, and its resources are refreshed
+// periodically when they are derived from the remote cluster.
+func (p *Provider) NotifyNodeStatus(ctx context.Context, changed func(*corev1.Node)) {
+	go p.probeRemote(ctx, changed)
+
+	if p.cfg.Node.Resources.Mode == NodeResourcesModeRemote {
+		go p.refreshResources(ctx, changed)
+	}
+}
+
+// probeRemote periodically probes the remote API server, marking the AK node
+// as not ready when the configured number of consecutive probes fail.
+func (p *Provider) probeRemote(ctx context.Context, changed func(*corev1.Node)) {
+	interval := p.cfg.Node.Health.ProbeInterval.Duration
+	if interval == 0 {
+		interval = defaultProbeInterval
+	}
+	threshold := p.cfg.Node.Health.FailureThreshold
+	if threshold == 0 {
+		threshold = defaultFailureThreshold
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	failures := make(map[string]int, len(p.remotes))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		// We consider a remote cluster to be unreachable once we've failed
+		// enough consecutive probes, and the node to be ready as long as at
+		// least one remote cluster is reachable.
+		unreachable := 0
+		var err error
+		for _, rc := range p.remotes {
+			perr := rc.Discovery().RESTClient().Get().AbsPath("/healthz").Do(ctx).Error()
+			if perr == nil {
+				failures[rc.Name] = 0
+				continue
+			}
+			failures[rc.Name]++
+			log.G(ctx).WithError(perr).WithField("remote", rc.Name).WithField("failures", failures[rc.Name]).Debug("cannot probe remote API server")
+			if failures[rc.Name] >= threshold {
+				unreachable++
+				err = errors.Wrapf(perr, "remote cluster %q", rc.Name)
+			}
+		}
+		if unreachable < len(p.remotes) {
+			err = nil
+		}
+
+		n := p.setNodeCondition(ReadyCondition(err))
+		if n != nil {
+			changed(n)
+		}
+	}
+}
+
+// setNodeCondition sets the supplied condition on the AK node. It returns a
+// copy of the updated node if the condition's status changed, and nil
+// otherwise.
+func (p *Provider) setNodeCondition(c corev1.NodeCondition) *corev1.Node {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.node == nil {
+		return nil
+	}
+
+	existing := GetNodeCondition(p.node.Status.Conditions, c.Type)
+	p.node.Status.Conditions = SetNodeCondition(p.node.Status.Conditions, c)
+	if existing.Status == c.Status {
+		return nil
+	}
+	return p.node.DeepCopy()
+}
+
+// refreshMetrics periodically refreshes metrics that are derived from the state
+// of the remote clusters.
+func (p *Provider) refreshMetrics(ctx context.Context) {
+	t := time.NewTicker(defaultMetricsRefreshInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		for _, rc := range p.remotes {
+			if err := p.metrics.Refresh(ctx, rc, p.nodeName); err != nil {
+				log.G(ctx).WithError(err).Debug("cannot refresh metrics")
+			}
+		}
+	}
+}
+
+// refreshResources periodically refreshes the AK node's resources.
+func (p *Provider) refreshResources(ctx context.Context, changed func(*corev1.Node)) {
+	interval := p.cfg.Node.Resources.RefreshInterval.Duration
+	if interval == 0 {
+		interval = defaultNodeRefreshInterval
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		c, a, err := p.nodeResources(ctx)
+		if err != nil {
+			log.G(ctx).WithError(err).Error("cannot refresh node resources")
+			continue
+		}
+
+		p.mu.Lock()
+		if p.node == nil {
+			p.mu.Unlock()
+			continue
+		}
+		p.node.Status.Capacity = c
+		p.node.Status.Allocatable = a
+		n := p.node.DeepCopy()
+		p.mu.Unlock()
+
+		changed(n)
+	}
 }
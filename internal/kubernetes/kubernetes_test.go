@@ -0,0 +1,384 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	kresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+type dependencyFetcherFn func(ctx context.Context, pod *corev1.Pod) ([]runtime.Object, error)
+
+func (fn dependencyFetcherFn) Fetch(ctx context.Context, pod *corev1.Pod) ([]runtime.Object, error) {
+	return fn(ctx, pod)
+}
+
+// recorded closes the supplied recorder and returns the events it recorded.
+func recorded(r *record.FakeRecorder) []string {
+	close(r.Events)
+	var got []string
+	for e := range r.Events {
+		got = append(got, e)
+	}
+	return got
+}
+
+func newTestProvider(t *testing.T, rc RemoteCluster, r record.EventRecorder) *Provider {
+	t.Helper()
+	m, err := NewMetrics(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("NewMetrics(...): %s", err)
+	}
+	mu, err := NewPodMutator(nil)
+	if err != nil {
+		t.Fatalf("NewPodMutator(...): %s", err)
+	}
+	return &Provider{
+		dependencies: dependencyFetcherFn(func(_ context.Context, _ *corev1.Pod) ([]runtime.Object, error) { return nil, nil }),
+		remotes:      []RemoteCluster{rc},
+		placer:       PlacerFn(func(_ context.Context, _ *corev1.Pod, rcs []RemoteCluster) (RemoteCluster, error) { return rcs[0], nil }),
+		namer:        remote.HashNamespaceNamer,
+		mutator:      mu,
+		quotas:       NewNamespaceQuotaApplicator(NamespacesConfig{}, "coolnode", remote.HashNamespaceNamer),
+		failed:       NewFailedPods(),
+		metrics:      m,
+		recorder:     r,
+		nodeName:     "coolnode",
+	}
+}
+
+// emptyRemote returns a RemoteCluster in which neither the pod nor its
+// namespace exist, and which returns the supplied errors when creating a pod
+// as a dry run, and otherwise.
+func emptyRemote(errDryRun, errCreate error) RemoteCluster {
+	return RemoteCluster{
+		Name: "cool",
+		Client: Client{ClientApplicator: resource.ClientApplicator{
+			Client: &test.MockClient{
+				MockGet: test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{}, "")),
+				MockCreate: func(_ context.Context, obj runtime.Object, o ...client.CreateOption) error {
+					co := &client.CreateOptions{}
+					co.ApplyOptions(o)
+					if _, ok := obj.(*corev1.Pod); !ok {
+						return nil
+					}
+					if len(co.DryRun) > 0 {
+						return errDryRun
+					}
+					return errCreate
+				},
+				MockDelete: func(_ context.Context, _ runtime.Object, _ ...client.DeleteOption) error { return nil },
+			},
+			Applicator: resource.ApplyFn(func(_ context.Context, _ runtime.Object, _ ...resource.ApplyOption) error { return nil }),
+		}},
+	}
+}
+
+func TestProviderApplyPodDependencies(t *testing.T) {
+	errNotFound := kerrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, "coolcm")
+	errBoom := errors.New("boom")
+	lcl := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "coolns", Name: "coolpod"}}
+
+	type want struct {
+		err    error
+		events []string
+	}
+	cases := map[string]struct {
+		reason string
+		deps   DependencyFetcher
+		want   want
+	}{
+		"DependencyMissing": {
+			reason: "A missing dependency should be recorded as an event",
+			deps: dependencyFetcherFn(func(_ context.Context, _ *corev1.Pod) ([]runtime.Object, error) {
+				return nil, errors.Wrap(errNotFound, "cannot get config map")
+			}),
+			want: want{
+				err:    errors.Wrap(errors.Wrap(errNotFound, "cannot get config map"), "cannot fetch local pod dependencies"),
+				events: []string{"Warning DependencyMissing Cannot find pod dependency: " + errNotFound.Error()},
+			},
+		},
+		"FetchError": {
+			reason: "Errors other than a missing dependency should not be recorded as an event",
+			deps: dependencyFetcherFn(func(_ context.Context, _ *corev1.Pod) ([]runtime.Object, error) {
+				return nil, errBoom
+			}),
+			want: want{
+				err: errors.Wrap(errBoom, "cannot fetch local pod dependencies"),
+			},
+		},
+		"NamespaceCreated": {
+			reason: "Creating the remote namespace should be recorded as an event",
+			deps: dependencyFetcherFn(func(_ context.Context, _ *corev1.Pod) ([]runtime.Object, error) {
+				return nil, nil
+			}),
+			want: want{
+				events: []string{"Normal RemoteNamespaceCreated Created namespace " + remote.NamespaceName("coolnode", "coolns") + " in remote cluster cool"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := record.NewFakeRecorder(10)
+			rc := emptyRemote(nil, nil)
+			p := newTestProvider(t, rc, r)
+			p.dependencies = tc.deps
+
+			err := p.ApplyPodDependencies(context.Background(), rc, lcl)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\np.ApplyPodDependencies(...): -want error, +got error: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.events, recorded(r)); diff != "" {
+				t.Errorf("\n%s\np.ApplyPodDependencies(...): -want events, +got events: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestProviderCreatePod(t *testing.T) {
+	errBoom := errors.New("boom")
+	lcl := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "coolns", Name: "coolpod"}}
+	failingPatch := `[{"op": "test", "path": "/spec/hostname", "value": "wat"}]`
+	errPatch := func() error {
+		p, _ := jsonpatch.DecodePatch([]byte(failingPatch))
+		j, _ := json.Marshal(&corev1.Pod{})
+		_, err := p.Apply(j)
+		return err
+	}()
+	errMutate := errors.Wrapf(errPatch, "cannot apply patch of pod mutation %d", 0)
+	created := "Normal RemoteNamespaceCreated Created namespace " + remote.NamespaceName("coolnode", "coolns") + " in remote cluster cool"
+	errInvalid := kerrors.NewInvalid(schema.GroupKind{Kind: "Pod"}, "coolpod", nil)
+	rejected := "Remote cluster cool would reject pod: " + errInvalid.Error()
+
+	type want struct {
+		err    error
+		events []string
+		status *corev1.PodStatus
+	}
+	cases := map[string]struct {
+		reason    string
+		errDryRun error
+		errCreate error
+		errStatus error
+		mutations []PodMutation
+		want      want
+	}{
+		"MutateFailed": {
+			reason:    "Failing to mutate the remote pod should be recorded as an event",
+			mutations: []PodMutation{{Patch: failingPatch}},
+			want: want{
+				err:    errors.Wrap(errMutate, "cannot mutate remote pod"),
+				events: []string{created, "Warning RemoteCreateFailed Cannot mutate pod: " + errMutate.Error()},
+			},
+		},
+		"CreateFailed": {
+			reason:    "Failing to create the remote pod should be recorded as an event",
+			errCreate: errBoom,
+			want: want{
+				err:    errors.Wrap(errBoom, "cannot apply remote pod"),
+				events: []string{created, "Warning RemoteCreateFailed Cannot create pod in remote cluster cool: boom"},
+			},
+		},
+		"Rejected": {
+			reason:    "A pod the remote cluster would reject should be failed and recorded as an event",
+			errDryRun: errInvalid,
+			want: want{
+				events: []string{created, "Warning RemoteAdmissionRejected " + rejected},
+				status: &corev1.PodStatus{Phase: corev1.PodFailed, Reason: ReasonRemoteAdmissionRejected, Message: rejected},
+			},
+		},
+		"RejectedStatusUpdateFailed": {
+			reason:    "Failing to record a rejected pod's status should return an error",
+			errDryRun: errInvalid,
+			errStatus: errBoom,
+			want: want{
+				err:    errors.Wrap(errBoom, "cannot update local pod status"),
+				events: []string{created, "Warning RemoteAdmissionRejected " + rejected},
+				status: &corev1.PodStatus{Phase: corev1.PodFailed, Reason: ReasonRemoteAdmissionRejected, Message: rejected},
+			},
+		},
+		"Created": {
+			reason: "Successfully creating the remote pod should not be recorded as a failure",
+			want: want{
+				events: []string{created},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := record.NewFakeRecorder(10)
+			rc := emptyRemote(tc.errDryRun, tc.errCreate)
+			p := newTestProvider(t, rc, r)
+			m, err := NewPodMutator(tc.mutations)
+			if err != nil {
+				t.Fatalf("NewPodMutator(...): %s", err)
+			}
+			p.mutator = m
+
+			var status *corev1.PodStatus
+			p.local = Client{ClientApplicator: resource.ClientApplicator{Client: &test.MockClient{
+				MockStatusUpdate: func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+					status = &obj.(*corev1.Pod).Status
+					return tc.errStatus
+				},
+			}}}
+
+			err = p.CreatePod(context.Background(), lcl)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\np.CreatePod(...): -want error, +got error: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.events, recorded(r)); diff != "" {
+				t.Errorf("\n%s\np.CreatePod(...): -want events, +got events: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.status, status); diff != "" {
+				t.Errorf("\n%s\np.CreatePod(...): -want local status, +got local status: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestProviderNotifyPodHandler(t *testing.T) {
+	nodeName := "coolnode"
+	ns := "coolns"
+
+	pod := func(node string, phase corev1.PodPhase, reason string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: remote.NamespaceName(nodeName, ns),
+				Name:      "coolpod",
+				Labels:    map[string]string{remote.LabelKeyNodeName: node, remote.LabelKeyNamespace: ns},
+			},
+			Status: corev1.PodStatus{Phase: phase, Reason: reason, Message: "The node was low on resource: memory."},
+		}
+	}
+
+	type args struct {
+		old *corev1.Pod
+		new *corev1.Pod
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   []string
+	}{
+		"Evicted": {
+			reason: "A remote pod being evicted should be recorded as an event",
+			args: args{
+				old: pod(nodeName, corev1.PodRunning, ""),
+				new: pod(nodeName, corev1.PodFailed, reasonEvicted),
+			},
+			want: []string{"Warning RemotePodEvicted Pod was evicted from remote cluster cool: The node was low on resource: memory."},
+		},
+		"AlreadyEvicted": {
+			reason: "A remote pod that was already evicted should not be recorded again",
+			args: args{
+				old: pod(nodeName, corev1.PodFailed, reasonEvicted),
+				new: pod(nodeName, corev1.PodFailed, reasonEvicted),
+			},
+		},
+		"OtherNode": {
+			reason: "An evicted remote pod created on behalf of another node should not be recorded",
+			args: args{
+				old: pod("other", corev1.PodRunning, ""),
+				new: pod("other", corev1.PodFailed, reasonEvicted),
+			},
+		},
+		"Failed": {
+			reason: "A remote pod that failed for reasons other than eviction should not be recorded",
+			args: args{
+				old: pod(nodeName, corev1.PodRunning, ""),
+				new: pod(nodeName, corev1.PodFailed, "Error"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := record.NewFakeRecorder(10)
+			rc := emptyRemote(nil, nil)
+			p := newTestProvider(t, rc, r)
+			p.local = Client{ClientApplicator: resource.ClientApplicator{Client: &test.MockClient{MockGet: test.NewMockGetFn(nil)}}}
+
+			var changed *corev1.Pod
+			h := p.notifyPodHandler(context.Background(), rc, func(pod *corev1.Pod) { changed = pod })
+			h.OnUpdate(tc.args.old, tc.args.new)
+
+			if diff := cmp.Diff(tc.want, recorded(r)); diff != "" {
+				t.Errorf("\n%s\nh.OnUpdate(...): -want events, +got events: \n%s\n", tc.reason, diff)
+			}
+			if changed == nil {
+				t.Errorf("\n%s\nh.OnUpdate(...): want changed pod to be notified", tc.reason)
+			}
+		})
+	}
+}
+
+func TestProviderConfigureNode(t *testing.T) {
+	errBoom := errors.New("boom")
+	static := map[string]string{"cpu": "100"}
+
+	type want struct {
+		capacity    corev1.ResourceList
+		allocatable corev1.ResourceList
+	}
+	cases := map[string]struct {
+		reason string
+		mode   NodeResourcesMode
+		want   want
+	}{
+		"Static": {
+			reason: "The node should present its static resources",
+			mode:   NodeResourcesModeStatic,
+			want: want{
+				capacity:    corev1.ResourceList{corev1.ResourceCPU: kresource.MustParse("100")},
+				allocatable: corev1.ResourceList{corev1.ResourceCPU: kresource.MustParse("100")},
+			},
+		},
+		"RemoteError": {
+			reason: "The node should fall back to its static resources when its remote resources can't be determined",
+			mode:   NodeResourcesModeRemote,
+			want: want{
+				capacity:    corev1.ResourceList{corev1.ResourceCPU: kresource.MustParse("100")},
+				allocatable: corev1.ResourceList{corev1.ResourceCPU: kresource.MustParse("100")},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rc := RemoteCluster{Name: "cool", Client: Client{ClientApplicator: resource.ClientApplicator{
+				Client: &test.MockClient{MockList: test.NewMockListFn(errBoom)},
+			}}}
+			p := newTestProvider(t, rc, record.NewFakeRecorder(10))
+			p.cfg.Node.Resources = NodeResourcesConfig{Mode: tc.mode, Allocatable: static}
+
+			n := &corev1.Node{}
+			p.ConfigureNode(context.Background(), n)
+			if diff := cmp.Diff(tc.want.capacity, n.Status.Capacity); diff != "" {
+				t.Errorf("\n%s\np.ConfigureNode(...): -want capacity, +got capacity: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.allocatable, n.Status.Allocatable); diff != "" {
+				t.Errorf("\n%s\np.ConfigureNode(...): -want allocatable, +got allocatable: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+const (
+	metricsNamespace = "actual_kubelets"
+
+	defaultMetricsRefreshInterval = 30 * time.Second
+)
+
+// Operations for which metrics are recorded.
+const (
+	OperationCreatePod            = "CreatePod"
+	OperationUpdatePod            = "UpdatePod"
+	OperationDeletePod            = "DeletePod"
+	OperationApplyPodDependencies = "ApplyPodDependencies"
+)
+
+// Results of operations for which metrics are recorded.
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+)
+
+// Metrics exposed by the provider.
+type Metrics struct {
+	operations        *prometheus.CounterVec
+	operationDuration *prometheus.HistogramVec
+	pods              *prometheus.GaugeVec
+	namespaces        *prometheus.GaugeVec
+	informerLag       *prometheus.HistogramVec
+}
+
+// NewMetrics returns provider metrics registered with the supplied registerer.
+func NewMetrics(r prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "operations_total",
+			Help:      "Total number of provider operations, by outcome.",
+		}, []string{"operation", "result"}),
+		operationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "operation_duration_seconds",
+			Help:      "Latency of provider operations, including calls to remote API servers.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "result"}),
+		pods: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "pods",
+			Help:      "Number of remote pods running on behalf of this node.",
+		}, []string{"remote"}),
+		namespaces: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "remote_namespaces",
+			Help:      "Number of remote namespaces created on behalf of this node.",
+		}, []string{"remote"}),
+		informerLag: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "pod_informer_lag_seconds",
+			Help:      "Time between a remote pod being written and the pod informer observing the write.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"remote"}),
+	}
+
+	for _, c := range []prometheus.Collector{m.operations, m.operationDuration, m.pods, m.namespaces, m.informerLag} {
+		if err := r.Register(c); err != nil {
+			return nil, errors.Wrap(err, "cannot register metric")
+		}
+	}
+
+	return m, nil
+}
+
+// ObserveOperation records the outcome and latency of the supplied operation,
+// which started at the supplied time. It's intended to be deferred with a
+// pointer to the operation's named error return value.
+func (m *Metrics) ObserveOperation(operation string, started time.Time, err *error) {
+	result := ResultSuccess
+	if err != nil && *err != nil {
+		result = ResultError
+	}
+	m.operations.WithLabelValues(operation, result).Inc()
+	m.operationDuration.WithLabelValues(operation, result).Observe(time.Since(started).Seconds())
+}
+
+// ObserveInformerLag records the time between the supplied remote pod last
+// being written to and the pod informer observing it.
+func (m *Metrics) ObserveInformerLag(remoteName string, pod *corev1.Pod) {
+	// NOTE(negz): Managed fields record when the remote API server processed
+	// each write. Our lag will be skewed by any clock difference between AK
+	// and the remote API server.
+	var last time.Time
+	for _, mf := range pod.GetManagedFields() {
+		if mf.Time != nil && mf.Time.After(last) {
+			last = mf.Time.Time
+		}
+	}
+	if last.IsZero() {
+		return
+	}
+	m.informerLag.WithLabelValues(remoteName).Observe(time.Since(last).Seconds())
+}
+
+// Refresh the gauges of pods and namespaces in the supplied remote cluster that
+// were created on behalf of the supplied node.
+func (m *Metrics) Refresh(ctx context.Context, rc RemoteCluster, nodeName string) error {
+	pl := &corev1.PodList{}
+	if err := rc.List(ctx, pl, client.MatchingLabels{remote.LabelKeyNodeName: nodeName}); err != nil {
+		return errors.Wrapf(err, "cannot list pods in remote cluster %q", rc.Name)
+	}
+	m.pods.WithLabelValues(rc.Name).Set(float64(len(pl.Items)))
+
+	nl := &corev1.NamespaceList{}
+	if err := rc.List(ctx, nl, client.MatchingLabels{remote.LabelKeyNodeName: nodeName}); err != nil {
+		return errors.Wrapf(err, "cannot list namespaces in remote cluster %q", rc.Name)
+	}
+	m.namespaces.WithLabelValues(rc.Name).Set(float64(len(nl.Items)))
+
+	return nil
+}
+
+// ServeMetrics serves the metrics gathered by the supplied gatherer on the
+// supplied port until the supplied context is done.
+func ServeMetrics(ctx context.Context, port int, g prometheus.Gatherer) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(g, promhttp.HandlerOpts{}))
+	srv := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.G(ctx).WithError(err).Error("cannot serve metrics")
+	}
+}
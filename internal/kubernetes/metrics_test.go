@@ -0,0 +1,95 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestMetricsObserveOperation(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	m, err := NewMetrics(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("NewMetrics(...): %s", err)
+	}
+
+	var success, failure error = nil, errBoom
+	m.ObserveOperation(OperationCreatePod, time.Now(), &success)
+	m.ObserveOperation(OperationCreatePod, time.Now(), &failure)
+	m.ObserveOperation(OperationCreatePod, time.Now(), &failure)
+
+	want := map[string]float64{ResultSuccess: 1, ResultError: 2}
+	got := map[string]float64{
+		ResultSuccess: testutil.ToFloat64(m.operations.WithLabelValues(OperationCreatePod, ResultSuccess)),
+		ResultError:   testutil.ToFloat64(m.operations.WithLabelValues(OperationCreatePod, ResultError)),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("m.ObserveOperation(...): -want, +got: \n%s\n", diff)
+	}
+}
+
+func TestMetricsRefresh(t *testing.T) {
+	errBoom := errors.New("boom")
+	nodeName := "coolnode"
+
+	type want struct {
+		pods       float64
+		namespaces float64
+		err        error
+	}
+	cases := map[string]struct {
+		reason string
+		rc     RemoteCluster
+		want   want
+	}{
+		"ListPodsError": {
+			reason: "Errors listing remote pods should be returned",
+			rc:     remoteCluster("a", &test.MockClient{MockList: test.NewMockListFn(errBoom)}),
+			want: want{
+				err: errors.Wrapf(errBoom, "cannot list pods in remote cluster %q", "a"),
+			},
+		},
+		"Success": {
+			reason: "Gauges should be set to the number of remote pods and namespaces",
+			rc: remoteCluster("a", &test.MockClient{MockList: test.NewMockListFn(nil, func(obj runtime.Object) error {
+				switch l := obj.(type) {
+				case *corev1.PodList:
+					l.Items = make([]corev1.Pod, 3)
+				case *corev1.NamespaceList:
+					l.Items = make([]corev1.Namespace, 2)
+				}
+				return nil
+			})}),
+			want: want{pods: 3, namespaces: 2},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			m, err := NewMetrics(prometheus.NewRegistry())
+			if err != nil {
+				t.Fatalf("NewMetrics(...): %s", err)
+			}
+			err = m.Refresh(context.Background(), tc.rc, nodeName)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nm.Refresh(...): -want error, +got error: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.pods, testutil.ToFloat64(m.pods.WithLabelValues(tc.rc.Name))); diff != "" {
+				t.Errorf("\n%s\nm.Refresh(...): -want pods, +got pods: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.namespaces, testutil.ToFloat64(m.namespaces.WithLabelValues(tc.rc.Name))); diff != "" {
+				t.Errorf("\n%s\nm.Refresh(...): -want namespaces, +got namespaces: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
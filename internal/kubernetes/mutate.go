@@ -0,0 +1,154 @@
+package kubernetes
+
+import (
+	"encoding/json"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+)
+
+type podMutation struct {
+	PodMutation
+
+	namespaces map[string]bool
+	selector   labels.Selector
+	patch      jsonpatch.Patch
+}
+
+// A PodMutator applies the configured mutations to remote pods, for example
+// in order to steer them onto particular remote node pools.
+type PodMutator struct {
+	mutations []podMutation
+}
+
+// NewPodMutator returns a PodMutator that applies the supplied mutations, in
+// order. It returns an error if any mutation has an invalid selector or patch.
+func NewPodMutator(mm []PodMutation) (*PodMutator, error) {
+	m := &PodMutator{mutations: make([]podMutation, len(mm))}
+	for i, pm := range mm {
+		m.mutations[i] = podMutation{PodMutation: pm, selector: labels.Everything()}
+
+		if len(pm.Namespaces) > 0 {
+			m.mutations[i].namespaces = make(map[string]bool, len(pm.Namespaces))
+			for _, ns := range pm.Namespaces {
+				m.mutations[i].namespaces[ns] = true
+			}
+		}
+
+		if pm.Selector != "" {
+			s, err := labels.Parse(pm.Selector)
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot parse selector of pod mutation %d", i)
+			}
+			m.mutations[i].selector = s
+		}
+
+		if pm.Patch != "" {
+			p, err := jsonpatch.DecodePatch([]byte(pm.Patch))
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot decode patch of pod mutation %d", i)
+			}
+			m.mutations[i].patch = p
+		}
+	}
+	return m, nil
+}
+
+func (pm podMutation) matches(local *corev1.Pod) bool {
+	if pm.namespaces != nil && !pm.namespaces[local.GetNamespace()] {
+		return false
+	}
+	return pm.selector.Matches(labels.Set(local.GetLabels()))
+}
+
+// Mutate the supplied remote pod, which must have been prepared by PreparePod,
+// by applying each mutation that selects the supplied local pod. Mutations are
+// selected by the local pod's namespace and labels, not the remote pod's.
+func (m *PodMutator) Mutate(local, remote *corev1.Pod) error {
+	for i, pm := range m.mutations {
+		if !pm.matches(local) {
+			continue
+		}
+
+		mutateMetadata(pm.PodMutation, remote)
+
+		for _, t := range pm.Tolerations {
+			addToleration(remote, t)
+		}
+
+		if len(pm.NodeSelector) > 0 && remote.Spec.NodeSelector == nil {
+			remote.Spec.NodeSelector = map[string]string{}
+		}
+		for k, v := range pm.NodeSelector {
+			remote.Spec.NodeSelector[k] = v
+		}
+
+		if pm.PriorityClassName != "" {
+			remote.Spec.PriorityClassName = pm.PriorityClassName
+		}
+
+		if pm.RuntimeClassName != "" {
+			rcn := pm.RuntimeClassName
+			remote.Spec.RuntimeClassName = &rcn
+		}
+
+		if pm.patch == nil {
+			continue
+		}
+		if err := applyPatch(pm.patch, remote); err != nil {
+			return errors.Wrapf(err, "cannot apply patch of pod mutation %d", i)
+		}
+	}
+	return nil
+}
+
+// MutateMetadata applies the labels and annotations of each mutation that
+// selects the supplied local pod to the supplied remote pod. Most of a pod's
+// spec is immutable, so only its metadata is mutated when it is updated.
+func (m *PodMutator) MutateMetadata(local, remote *corev1.Pod) {
+	for _, pm := range m.mutations {
+		if pm.matches(local) {
+			mutateMetadata(pm.PodMutation, remote)
+		}
+	}
+}
+
+func mutateMetadata(pm PodMutation, pod *corev1.Pod) {
+	if len(pm.Labels) > 0 {
+		meta.AddLabels(pod, pm.Labels)
+	}
+	if len(pm.Annotations) > 0 {
+		meta.AddAnnotations(pod, pm.Annotations)
+	}
+}
+
+func addToleration(pod *corev1.Pod, t corev1.Toleration) {
+	for _, existing := range pod.Spec.Tolerations {
+		if equality.Semantic.DeepEqual(existing, t) {
+			return
+		}
+	}
+	pod.Spec.Tolerations = append(pod.Spec.Tolerations, t)
+}
+
+func applyPatch(p jsonpatch.Patch, pod *corev1.Pod) error {
+	j, err := json.Marshal(pod)
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal pod")
+	}
+	patched, err := p.Apply(j)
+	if err != nil {
+		return err
+	}
+	out := &corev1.Pod{}
+	if err := json.Unmarshal(patched, out); err != nil {
+		return errors.Wrap(err, "cannot unmarshal patched pod")
+	}
+	*pod = *out
+	return nil
+}
@@ -0,0 +1,130 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestPodMutatorMutate(t *testing.T) {
+	ns := "coolns"
+	rcn := "gvisor"
+
+	local := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Labels: map[string]string{"app": "cool"}}}
+
+	failingPatch := `[{"op": "test", "path": "/spec/hostname", "value": "wat"}]`
+	errPatch := func() error {
+		p, _ := jsonpatch.DecodePatch([]byte(failingPatch))
+		j, _ := json.Marshal(&corev1.Pod{})
+		_, err := p.Apply(j)
+		return err
+	}()
+
+	type args struct {
+		mm     []PodMutation
+		remote *corev1.Pod
+	}
+	type want struct {
+		remote *corev1.Pod
+		err    error
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"OtherNamespace": {
+			reason: "Mutations that select other namespaces should not be applied",
+			args: args{
+				mm:     []PodMutation{{Namespaces: []string{"other"}, Labels: map[string]string{"cool": "true"}}},
+				remote: &corev1.Pod{},
+			},
+			want: want{remote: &corev1.Pod{}},
+		},
+		"NotSelected": {
+			reason: "Mutations whose selector does not match the local pod should not be applied",
+			args: args{
+				mm:     []PodMutation{{Selector: "app=other", Labels: map[string]string{"cool": "true"}}},
+				remote: &corev1.Pod{},
+			},
+			want: want{remote: &corev1.Pod{}},
+		},
+		"Mutated": {
+			reason: "Mutations that select the local pod should be applied",
+			args: args{
+				mm: []PodMutation{{
+					Namespaces:        []string{ns},
+					Selector:          "app in (cool, cooler)",
+					Tolerations:       []corev1.Toleration{{Key: "pool", Value: "ak", Effect: corev1.TaintEffectNoSchedule}},
+					NodeSelector:      map[string]string{"pool": "ak"},
+					PriorityClassName: "high",
+					RuntimeClassName:  rcn,
+					Labels:            map[string]string{"cool": "true"},
+					Annotations:       map[string]string{"cool": "very"},
+				}},
+				remote: &corev1.Pod{
+					Spec: corev1.PodSpec{Tolerations: []corev1.Toleration{{Key: "pool", Value: "ak", Effect: corev1.TaintEffectNoSchedule}}},
+				},
+			},
+			want: want{remote: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      map[string]string{"cool": "true"},
+					Annotations: map[string]string{"cool": "very"},
+				},
+				Spec: corev1.PodSpec{
+					Tolerations:       []corev1.Toleration{{Key: "pool", Value: "ak", Effect: corev1.TaintEffectNoSchedule}},
+					NodeSelector:      map[string]string{"pool": "ak"},
+					PriorityClassName: "high",
+					RuntimeClassName:  &rcn,
+				},
+			}},
+		},
+		"Patched": {
+			reason: "JSON patches should be applied after other mutations",
+			args: args{
+				mm: []PodMutation{{
+					NodeSelector: map[string]string{"pool": "ak"},
+					Patch:        `[{"op": "replace", "path": "/spec/nodeSelector/pool", "value": "patched"}]`,
+				}},
+				remote: &corev1.Pod{},
+			},
+			want: want{remote: &corev1.Pod{
+				Spec: corev1.PodSpec{NodeSelector: map[string]string{"pool": "patched"}},
+			}},
+		},
+		"PatchError": {
+			reason: "Errors applying a JSON patch should be returned",
+			args: args{
+				mm:     []PodMutation{{Patch: failingPatch}},
+				remote: &corev1.Pod{},
+			},
+			want: want{
+				remote: &corev1.Pod{},
+				err:    errors.Wrapf(errPatch, "cannot apply patch of pod mutation %d", 0),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			m, err := NewPodMutator(tc.args.mm)
+			if err != nil {
+				t.Fatalf("NewPodMutator(...): %s", err)
+			}
+			err = m.Mutate(local, tc.args.remote)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nm.Mutate(...): -want error, +got error: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.remote, tc.args.remote); diff != "" {
+				t.Errorf("\n%s\nm.Mutate(...): -want, +got: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
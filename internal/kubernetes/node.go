@@ -0,0 +1,159 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+// Reasons a node may or may not be ready.
+const (
+	ReasonKubeletReady      = "KubeletReady"
+	ReasonRemoteUnreachable = "RemoteUnreachable"
+)
+
+// ReadyCondition returns a NodeReady condition. The node is ready if the
+// supplied error, which represents the most recent failure to reach any
+// remote API server, is nil.
+func ReadyCondition(err error) corev1.NodeCondition {
+	if err != nil {
+		return corev1.NodeCondition{
+			Type:               corev1.NodeReady,
+			Status:             corev1.ConditionFalse,
+			LastHeartbeatTime:  metav1.Now(),
+			LastTransitionTime: metav1.Now(),
+			Reason:             ReasonRemoteUnreachable,
+			Message:            fmt.Sprintf("AK cannot reach any remote API server: %s", err),
+		}
+	}
+	return corev1.NodeCondition{
+		Type:               corev1.NodeReady,
+		Status:             corev1.ConditionTrue,
+		LastHeartbeatTime:  metav1.Now(),
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonKubeletReady,
+		Message:            "AK can reach a remote API server.",
+	}
+}
+
+// SetNodeCondition sets the supplied condition, replacing any existing
+// condition of the same type. The existing condition's last transition time is
+// preserved if its status has not changed.
+func SetNodeCondition(cs []corev1.NodeCondition, c corev1.NodeCondition) []corev1.NodeCondition {
+	for i := range cs {
+		if cs[i].Type != c.Type {
+			continue
+		}
+		if cs[i].Status == c.Status {
+			c.LastTransitionTime = cs[i].LastTransitionTime
+		}
+		cs[i] = c
+		return cs
+	}
+	return append(cs, c)
+}
+
+// GetNodeCondition returns the condition of the supplied type, if any.
+func GetNodeCondition(cs []corev1.NodeCondition, ct corev1.NodeConditionType) corev1.NodeCondition {
+	for _, c := range cs {
+		if c.Type == ct {
+			return c
+		}
+	}
+	return corev1.NodeCondition{Type: ct, Status: corev1.ConditionUnknown}
+}
+
+// RemoteResources returns the capacity and allocatable resources of a remote
+// cluster consisting of the supplied nodes and pods. Capacity is the sum of
+// the allocatable resources of all ready, schedulable nodes. Allocatable is
+// the capacity less the resources requested by all pods running on those
+// nodes, except those created by the supplied node. Pods created by the
+// supplied node are omitted because they are accounted for by the local
+// scheduler.
+func RemoteResources(nodeName string, nodes []corev1.Node, pods []corev1.Pod) (capacity, allocatable corev1.ResourceList) {
+	capacity = corev1.ResourceList{}
+	ready := map[string]bool{}
+	for i := range nodes {
+		n := &nodes[i]
+		if n.Spec.Unschedulable || !isReady(n) {
+			continue
+		}
+		ready[n.GetName()] = true
+		addResources(capacity, n.Status.Allocatable)
+	}
+
+	allocatable = capacity.DeepCopy()
+	for i := range pods {
+		pod := &pods[i]
+		if !ready[pod.Spec.NodeName] {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if pod.GetLabels()[remote.LabelKeyNodeName] == nodeName {
+			continue
+		}
+		subResources(allocatable, podRequests(pod))
+	}
+
+	return capacity, allocatable
+}
+
+func isReady(n *corev1.Node) bool {
+	return GetNodeCondition(n.Status.Conditions, corev1.NodeReady).Status == corev1.ConditionTrue
+}
+
+// podRequests returns the resources requested by the supplied pod, using the
+// same logic as the scheduler. A pod requests the sum of its containers'
+// requests or the largest of its init containers' requests - whichever is
+// larger - plus its overhead, plus one 'pod'.
+func podRequests(pod *corev1.Pod) corev1.ResourceList {
+	reqs := corev1.ResourceList{}
+	for _, c := range pod.Spec.Containers {
+		addResources(reqs, c.Resources.Requests)
+	}
+	for _, c := range pod.Spec.InitContainers {
+		for name, q := range c.Resources.Requests {
+			if existing, ok := reqs[name]; !ok || q.Cmp(existing) > 0 {
+				reqs[name] = q.DeepCopy()
+			}
+		}
+	}
+	addResources(reqs, pod.Spec.Overhead)
+	addResources(reqs, corev1.ResourceList{corev1.ResourcePods: *resource.NewQuantity(1, resource.DecimalSI)})
+	return reqs
+}
+
+func addResources(to, from corev1.ResourceList) {
+	for name, q := range from {
+		existing, ok := to[name]
+		if !ok {
+			to[name] = q.DeepCopy()
+			continue
+		}
+		existing.Add(q)
+		to[name] = existing
+	}
+}
+
+// subResources subtracts the resources in from from those in to. Resources
+// that are not present in to are ignored. Resources are never reduced below
+// zero.
+func subResources(to, from corev1.ResourceList) {
+	for name, q := range from {
+		existing, ok := to[name]
+		if !ok {
+			continue
+		}
+		existing.Sub(q)
+		if existing.Sign() < 0 {
+			existing = *resource.NewQuantity(0, existing.Format)
+		}
+		to[name] = existing
+	}
+}
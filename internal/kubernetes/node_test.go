@@ -0,0 +1,192 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+func TestRemoteResources(t *testing.T) {
+	nodeName := "coolnode"
+
+	node := func(name string, ready corev1.ConditionStatus, unschedulable bool) corev1.Node {
+		return corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+			Status: corev1.NodeStatus{
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("4"),
+					corev1.ResourceMemory: resource.MustParse("16Gi"),
+					corev1.ResourcePods:   resource.MustParse("110"),
+				},
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}},
+			},
+		}
+	}
+	pod := func(node string, labels map[string]string, phase corev1.PodPhase) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels},
+			Spec: corev1.PodSpec{
+				NodeName: node,
+				Containers: []corev1.Container{
+					{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}}},
+					{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}}},
+				},
+				InitContainers: []corev1.Container{
+					{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("250m"),
+						corev1.ResourceMemory: resource.MustParse("1Gi"),
+					}}},
+				},
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+
+	type args struct {
+		nodes []corev1.Node
+		pods  []corev1.Pod
+	}
+	type want struct {
+		capacity    corev1.ResourceList
+		allocatable corev1.ResourceList
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoNodes": {
+			reason: "A cluster with no nodes has no resources",
+			want: want{
+				capacity:    corev1.ResourceList{},
+				allocatable: corev1.ResourceList{},
+			},
+		},
+		"UnavailableNodes": {
+			reason: "Nodes that are not ready or are unschedulable should not contribute resources",
+			args: args{
+				nodes: []corev1.Node{
+					node("a", corev1.ConditionTrue, false),
+					node("b", corev1.ConditionFalse, false),
+					node("c", corev1.ConditionTrue, true),
+				},
+			},
+			want: want{
+				capacity: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("4"),
+					corev1.ResourceMemory: resource.MustParse("16Gi"),
+					corev1.ResourcePods:   resource.MustParse("110"),
+				},
+				allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("4"),
+					corev1.ResourceMemory: resource.MustParse("16Gi"),
+					corev1.ResourcePods:   resource.MustParse("110"),
+				},
+			},
+		},
+		"RunningPods": {
+			reason: "Resources requested by running pods should not be allocatable, unless they were created by our node",
+			args: args{
+				nodes: []corev1.Node{
+					node("a", corev1.ConditionTrue, false),
+					node("b", corev1.ConditionTrue, false),
+				},
+				pods: []corev1.Pod{
+					pod("a", nil, corev1.PodRunning),
+					pod("b", nil, corev1.PodPending),
+					pod("a", nil, corev1.PodSucceeded),
+					pod("a", map[string]string{remote.LabelKeyNodeName: nodeName}, corev1.PodRunning),
+					pod("c", nil, corev1.PodRunning),
+				},
+			},
+			want: want{
+				capacity: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("8"),
+					corev1.ResourceMemory: resource.MustParse("32Gi"),
+					corev1.ResourcePods:   resource.MustParse("220"),
+				},
+				allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("6"),
+					corev1.ResourceMemory: resource.MustParse("30Gi"),
+					corev1.ResourcePods:   resource.MustParse("218"),
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c, a := RemoteResources(nodeName, tc.args.nodes, tc.args.pods)
+			eq := cmp.Comparer(func(a, b resource.Quantity) bool { return a.Cmp(b) == 0 })
+			if diff := cmp.Diff(tc.want.capacity, c, eq); diff != "" {
+				t.Errorf("\n%s\nRemoteResources(...): -want capacity, +got capacity: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.allocatable, a, eq); diff != "" {
+				t.Errorf("\n%s\nRemoteResources(...): -want allocatable, +got allocatable: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSetNodeCondition(t *testing.T) {
+	then := metav1.NewTime(time.Unix(0, 0))
+	now := metav1.Now()
+
+	type args struct {
+		cs []corev1.NodeCondition
+		c  corev1.NodeCondition
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   []corev1.NodeCondition
+	}{
+		"NewCondition": {
+			reason: "A condition of a new type should be appended",
+			args: args{
+				cs: []corev1.NodeCondition{{Type: corev1.NodeMemoryPressure}},
+				c:  corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionTrue, LastTransitionTime: now},
+			},
+			want: []corev1.NodeCondition{
+				{Type: corev1.NodeMemoryPressure},
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue, LastTransitionTime: now},
+			},
+		},
+		"UnchangedStatus": {
+			reason: "The last transition time should be preserved if the condition's status did not change",
+			args: args{
+				cs: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue, LastTransitionTime: then}},
+				c:  corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionTrue, LastTransitionTime: now, Reason: "Cool"},
+			},
+			want: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue, LastTransitionTime: then, Reason: "Cool"},
+			},
+		},
+		"ChangedStatus": {
+			reason: "The last transition time should be updated if the condition's status changed",
+			args: args{
+				cs: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue, LastTransitionTime: then}},
+				c:  corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionFalse, LastTransitionTime: now},
+			},
+			want: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionFalse, LastTransitionTime: now},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := SetNodeCondition(tc.args.cs, tc.args.c)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nSetNodeCondition(...): -want, +got: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
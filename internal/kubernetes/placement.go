@@ -0,0 +1,143 @@
+package kubernetes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+// DefaultPlacementKey is the label or annotation used to select the remote API
+// server a pod should be created in.
+const DefaultPlacementKey = "actual.vk/remote"
+
+// A RemoteCluster is a remote (backing) API server in which AK runs pods.
+type RemoteCluster struct {
+	// Name of this remote cluster.
+	Name string
+
+	Client
+}
+
+// A Placer determines which of the supplied remote clusters a new pod should be
+// created in.
+type Placer interface {
+	Place(ctx context.Context, pod *corev1.Pod, rcs []RemoteCluster) (RemoteCluster, error)
+}
+
+// A PlacerFn determines which of the supplied remote clusters a new pod should
+// be created in.
+type PlacerFn func(ctx context.Context, pod *corev1.Pod, rcs []RemoteCluster) (RemoteCluster, error)
+
+// Place the supplied pod in one of the supplied remote clusters.
+func (fn PlacerFn) Place(ctx context.Context, pod *corev1.Pod, rcs []RemoteCluster) (RemoteCluster, error) {
+	return fn(ctx, pod, rcs)
+}
+
+// NewPlacer returns a Placer for the supplied placement configuration.
+func NewPlacer(cfg PlacementConfig, nodeName string) Placer {
+	switch cfg.Policy {
+	case PlacementPolicyLeastLoaded:
+		return NewLeastLoadedPlacer(nodeName)
+	case PlacementPolicySelected:
+		key := cfg.Key
+		if key == "" {
+			key = DefaultPlacementKey
+		}
+		return NewSelectedPlacer(key, &RoundRobinPlacer{})
+	default:
+		return &RoundRobinPlacer{}
+	}
+}
+
+// A RoundRobinPlacer places each pod in the next remote cluster.
+type RoundRobinPlacer struct {
+	mu   sync.Mutex
+	next int
+}
+
+// Place the supplied pod in the next remote cluster.
+func (p *RoundRobinPlacer) Place(_ context.Context, _ *corev1.Pod, rcs []RemoteCluster) (RemoteCluster, error) {
+	if len(rcs) == 0 {
+		return RemoteCluster{}, errors.New("no remote clusters")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	rc := rcs[p.next%len(rcs)]
+	p.next = (p.next + 1) % len(rcs)
+	return rc, nil
+}
+
+// A LeastLoadedPlacer places each pod in the remote cluster that is running the
+// fewest pods on behalf of its node.
+type LeastLoadedPlacer struct {
+	nodeName string
+}
+
+// NewLeastLoadedPlacer returns a Placer that places each pod in the remote
+// cluster that is running the fewest pods on behalf of the supplied node.
+func NewLeastLoadedPlacer(nodeName string) *LeastLoadedPlacer {
+	return &LeastLoadedPlacer{nodeName: nodeName}
+}
+
+// Place the supplied pod in the least loaded remote cluster.
+func (p *LeastLoadedPlacer) Place(ctx context.Context, _ *corev1.Pod, rcs []RemoteCluster) (RemoteCluster, error) {
+	if len(rcs) == 0 {
+		return RemoteCluster{}, errors.New("no remote clusters")
+	}
+
+	least := -1
+	var placed RemoteCluster
+	for _, rc := range rcs {
+		l := &corev1.PodList{}
+		if err := rc.List(ctx, l, client.MatchingLabels{remote.LabelKeyNodeName: p.nodeName}); err != nil {
+			return RemoteCluster{}, errors.Wrapf(err, "cannot list pods in remote cluster %q", rc.Name)
+		}
+		if least < 0 || len(l.Items) < least {
+			least = len(l.Items)
+			placed = rc
+		}
+	}
+
+	return placed, nil
+}
+
+// A SelectedPlacer places each pod in the remote cluster named by one of its
+// labels or annotations.
+type SelectedPlacer struct {
+	key      string
+	fallback Placer
+}
+
+// NewSelectedPlacer returns a Placer that places each pod in the remote
+// cluster named by the value of its label or annotation with the supplied key.
+// Pods with no such label or annotation are placed by the supplied fallback
+// Placer.
+func NewSelectedPlacer(key string, fallback Placer) *SelectedPlacer {
+	return &SelectedPlacer{key: key, fallback: fallback}
+}
+
+// Place the supplied pod in its selected remote cluster.
+func (p *SelectedPlacer) Place(ctx context.Context, pod *corev1.Pod, rcs []RemoteCluster) (RemoteCluster, error) {
+	name, ok := pod.GetLabels()[p.key]
+	if !ok {
+		name, ok = pod.GetAnnotations()[p.key]
+	}
+	if !ok {
+		return p.fallback.Place(ctx, pod, rcs)
+	}
+
+	for _, rc := range rcs {
+		if rc.Name == name {
+			return rc, nil
+		}
+	}
+
+	return RemoteCluster{}, errors.Errorf("selected remote cluster %q does not exist", name)
+}
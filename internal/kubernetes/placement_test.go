@@ -0,0 +1,123 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func remoteCluster(name string, c client.Client) RemoteCluster {
+	return RemoteCluster{Name: name, Client: Client{ClientApplicator: resource.ClientApplicator{Client: c}}}
+}
+
+func TestPlace(t *testing.T) {
+	errBoom := errors.New("boom")
+	nodeName := "coolnode"
+
+	pods := func(n int) client.Client {
+		return &test.MockClient{
+			MockList: test.NewMockListFn(nil, func(obj runtime.Object) error {
+				obj.(*corev1.PodList).Items = make([]corev1.Pod, n)
+				return nil
+			}),
+		}
+	}
+	first := PlacerFn(func(_ context.Context, _ *corev1.Pod, rcs []RemoteCluster) (RemoteCluster, error) {
+		return rcs[0], nil
+	})
+
+	type want struct {
+		name string
+		err  error
+	}
+	cases := map[string]struct {
+		reason string
+		p      Placer
+		pod    *corev1.Pod
+		rcs    []RemoteCluster
+		want   want
+	}{
+		"LeastLoaded": {
+			reason: "The remote cluster running the fewest pods should be selected",
+			p:      NewLeastLoadedPlacer(nodeName),
+			pod:    &corev1.Pod{},
+			rcs:    []RemoteCluster{remoteCluster("a", pods(3)), remoteCluster("b", pods(1)), remoteCluster("c", pods(2))},
+			want:   want{name: "b"},
+		},
+		"LeastLoadedListError": {
+			reason: "Errors listing remote pods should be returned",
+			p:      NewLeastLoadedPlacer(nodeName),
+			pod:    &corev1.Pod{},
+			rcs:    []RemoteCluster{remoteCluster("a", &test.MockClient{MockList: test.NewMockListFn(errBoom)})},
+			want:   want{err: errors.Wrapf(errBoom, "cannot list pods in remote cluster %q", "a")},
+		},
+		"SelectedByLabel": {
+			reason: "The remote cluster named by the pod's label should be selected",
+			p:      NewSelectedPlacer(DefaultPlacementKey, first),
+			pod:    &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{DefaultPlacementKey: "b"}}},
+			rcs:    []RemoteCluster{remoteCluster("a", nil), remoteCluster("b", nil)},
+			want:   want{name: "b"},
+		},
+		"SelectedByAnnotation": {
+			reason: "The remote cluster named by the pod's annotation should be selected",
+			p:      NewSelectedPlacer(DefaultPlacementKey, first),
+			pod:    &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{DefaultPlacementKey: "b"}}},
+			rcs:    []RemoteCluster{remoteCluster("a", nil), remoteCluster("b", nil)},
+			want:   want{name: "b"},
+		},
+		"SelectedFallback": {
+			reason: "Pods that do not select a remote cluster should be placed by the fallback placer",
+			p:      NewSelectedPlacer(DefaultPlacementKey, first),
+			pod:    &corev1.Pod{},
+			rcs:    []RemoteCluster{remoteCluster("a", nil), remoteCluster("b", nil)},
+			want:   want{name: "a"},
+		},
+		"SelectedDoesNotExist": {
+			reason: "An error should be returned if the selected remote cluster does not exist",
+			p:      NewSelectedPlacer(DefaultPlacementKey, first),
+			pod:    &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{DefaultPlacementKey: "c"}}},
+			rcs:    []RemoteCluster{remoteCluster("a", nil), remoteCluster("b", nil)},
+			want:   want{err: errors.Errorf("selected remote cluster %q does not exist", "c")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := tc.p.Place(context.Background(), tc.pod, tc.rcs)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\np.Place(...): -want error, +got error: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.name, got.Name); diff != "" {
+				t.Errorf("\n%s\np.Place(...): -want, +got: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRoundRobinPlacerPlace(t *testing.T) {
+	rcs := []RemoteCluster{remoteCluster("a", nil), remoteCluster("b", nil)}
+	p := &RoundRobinPlacer{}
+
+	want := []string{"a", "b", "a"}
+	got := make([]string, 0, len(want))
+	for range want {
+		rc, err := p.Place(context.Background(), &corev1.Pod{}, rcs)
+		if err != nil {
+			t.Fatalf("p.Place(...): %s", err)
+		}
+		got = append(got, rc.Name)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("p.Place(...): -want, +got: \n%s\n", diff)
+	}
+}
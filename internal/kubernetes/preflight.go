@@ -0,0 +1,137 @@
+package kubernetes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// rejectingCauses are the causes of a Forbidden error that indicate the remote
+// cluster would reject the object being created, regardless of when or by whom
+// it was created. Other Forbidden errors may resolve themselves or be resolved
+// by an operator; for example an object can't be created in a namespace that
+// is being deleted, or by a client that lacks the RBAC permissions to do so.
+var rejectingCauses = map[metav1.CauseType]bool{
+	metav1.CauseType(field.ErrorTypeRequired):     true,
+	metav1.CauseType(field.ErrorTypeInvalid):      true,
+	metav1.CauseType(field.ErrorTypeForbidden):    true,
+	metav1.CauseType(field.ErrorTypeNotSupported): true,
+	metav1.CauseType(field.ErrorTypeDuplicate):    true,
+	metav1.CauseType(field.ErrorTypeTooLong):      true,
+	metav1.CauseType(field.ErrorTypeTooMany):      true,
+}
+
+// Preflight dry-runs the creation of the supplied remote pod, returning an
+// error if the remote cluster would reject it; for example because it is
+// invalid, or forbidden by an admission controller. Errors that don't
+// conclusively indicate the pod would be rejected, such as those from admission
+// webhooks that don't support dry runs or Forbidden errors for unknown reasons,
+// are ignored; creating the pod will surface them.
+func Preflight(ctx context.Context, c client.Writer, rmt *corev1.Pod) error {
+	err := c.Create(ctx, rmt.DeepCopy(), client.DryRunAll)
+	if rejected(err) {
+		return err
+	}
+	if err != nil {
+		log.G(ctx).WithError(err).Debug("ignoring inconclusive preflight dry run")
+	}
+	return nil
+}
+
+// rejected returns true if the supplied error indicates that the remote
+// cluster would reject the object being created. Errors are classified by
+// their reason and causes.
+func rejected(err error) bool {
+	if kerrors.IsInvalid(err) {
+		return true
+	}
+	if !kerrors.IsForbidden(err) {
+		return false
+	}
+	se, ok := err.(kerrors.APIStatus)
+	if !ok || se.Status().Details == nil {
+		return false
+	}
+	for _, c := range se.Status().Details.Causes {
+		if rejectingCauses[c.Type] {
+			return true
+		}
+	}
+	return false
+}
+
+// FailedPods tracks local pods that failed before they could be created in a
+// remote cluster. Such pods will never succeed, so rather than retrying them we
+// report them as failed until they're deleted.
+type FailedPods struct {
+	mu     sync.RWMutex
+	pods   map[types.NamespacedName]*corev1.Pod
+	notify func(*corev1.Pod)
+}
+
+// NewFailedPods returns a new FailedPods.
+func NewFailedPods() *FailedPods {
+	return &FailedPods{pods: map[types.NamespacedName]*corev1.Pod{}}
+}
+
+// Notify configures the function that will be called when a pod fails.
+func (f *FailedPods) Notify(changed func(*corev1.Pod)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.notify = changed
+}
+
+// Fail the supplied local pod for the supplied reason, returning the failed
+// pod.
+func (f *FailedPods) Fail(lcl *corev1.Pod, reason, message string) *corev1.Pod {
+	pod := lcl.DeepCopy()
+	pod.Status.Phase = corev1.PodFailed
+	pod.Status.Reason = reason
+	pod.Status.Message = message
+
+	f.mu.Lock()
+	f.pods[types.NamespacedName{Namespace: pod.GetNamespace(), Name: pod.GetName()}] = pod
+	notify := f.notify
+	f.mu.Unlock()
+
+	if notify != nil {
+		notify(pod.DeepCopy())
+	}
+	return pod.DeepCopy()
+}
+
+// Get the supplied failed pod, if it exists.
+func (f *FailedPods) Get(namespace, name string) (*corev1.Pod, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	pod, ok := f.pods[types.NamespacedName{Namespace: namespace, Name: name}]
+	if !ok {
+		return nil, false
+	}
+	return pod.DeepCopy(), true
+}
+
+// List all failed pods.
+func (f *FailedPods) List() []*corev1.Pod {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	pods := make([]*corev1.Pod, 0, len(f.pods))
+	for _, pod := range f.pods {
+		pods = append(pods, pod.DeepCopy())
+	}
+	return pods
+}
+
+// Forget the supplied failed pod, for example because it has been deleted.
+func (f *FailedPods) Forget(namespace, name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.pods, types.NamespacedName{Namespace: namespace, Name: name})
+}
@@ -0,0 +1,125 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+)
+
+func TestPreflight(t *testing.T) {
+	errInvalid := kerrors.NewInvalid(schema.GroupKind{Kind: "Pod"}, "coolpod", field.ErrorList{field.Required(field.NewPath("spec", "containers"), "")})
+	errForbidden := kerrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "coolpod", errors.New("exceeded quota"))
+	errForbiddenField := kerrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "coolpod", errors.New("host ports are forbidden"))
+	errForbiddenField.ErrStatus.Details.Causes = []metav1.StatusCause{{Type: metav1.CauseType(field.ErrorTypeForbidden), Field: "spec.containers[0].ports[0].hostPort"}}
+	errTerminating := kerrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "coolpod", errors.New("unable to create new content in namespace coolns because it is being terminated"))
+	errTerminatingCause := kerrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "coolpod", errors.New("namespace is terminating"))
+	errTerminatingCause.ErrStatus.Details.Causes = []metav1.StatusCause{{Type: corev1.NamespaceTerminatingCause}}
+	errUnauthorized := kerrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "coolpod", errors.New(`User "ak" cannot create resource "pods" in API group "" in the namespace "coolns"`))
+
+	create := func(err error) client.Writer {
+		return &test.MockClient{
+			MockCreate: func(_ context.Context, _ runtime.Object, o ...client.CreateOption) error {
+				co := &client.CreateOptions{}
+				co.ApplyOptions(o)
+				if len(co.DryRun) != 1 || co.DryRun[0] != metav1.DryRunAll {
+					return errors.New("not a dry run")
+				}
+				return err
+			},
+		}
+	}
+
+	cases := map[string]struct {
+		reason string
+		c      client.Writer
+		want   error
+	}{
+		"Invalid": {
+			reason: "Errors indicating the pod is invalid should be returned",
+			c:      create(errInvalid),
+			want:   errInvalid,
+		},
+		"ForbiddenField": {
+			reason: "Forbidden errors caused by a field of the pod should be returned",
+			c:      create(errForbiddenField),
+			want:   errForbiddenField,
+		},
+		"Forbidden": {
+			reason: "Forbidden errors for unknown reasons should be ignored",
+			c:      create(errForbidden),
+			want:   nil,
+		},
+		"NamespaceTerminating": {
+			reason: "Errors indicating the remote namespace is being deleted should be ignored",
+			c:      create(errTerminatingCause),
+			want:   nil,
+		},
+		"NamespaceTerminatingMessage": {
+			reason: "Errors from older API servers indicating the remote namespace is being deleted should be ignored",
+			c:      create(errTerminating),
+			want:   nil,
+		},
+		"Unauthorized": {
+			reason: "Errors indicating AK is not authorized to create pods should be ignored",
+			c:      create(errUnauthorized),
+			want:   nil,
+		},
+		"Inconclusive": {
+			reason: "Errors that don't indicate the remote cluster would reject the pod should be ignored",
+			c:      create(kerrors.NewBadRequest("webhook does not support dry run")),
+			want:   nil,
+		},
+		"Admitted": {
+			reason: "A pod the remote cluster would admit should pass preflight",
+			c:      create(nil),
+			want:   nil,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := Preflight(context.Background(), tc.c, &corev1.Pod{})
+			if diff := cmp.Diff(tc.want, got, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nPreflight(...): -want error, +got error: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestFailedPods(t *testing.T) {
+	lcl := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "coolns", Name: "coolpod"}}
+	want := lcl.DeepCopy()
+	want.Status = corev1.PodStatus{Phase: corev1.PodFailed, Reason: ReasonRemoteAdmissionRejected, Message: "nope"}
+
+	var notified *corev1.Pod
+	f := NewFailedPods()
+	f.Notify(func(pod *corev1.Pod) { notified = pod })
+	f.Fail(lcl, ReasonRemoteAdmissionRejected, "nope")
+
+	if diff := cmp.Diff(want, notified); diff != "" {
+		t.Errorf("f.Fail(...): -want notified, +got notified: \n%s\n", diff)
+	}
+	got, _ := f.Get("coolns", "coolpod")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("f.Get(...): -want, +got: \n%s\n", diff)
+	}
+	if diff := cmp.Diff([]*corev1.Pod{want}, f.List()); diff != "" {
+		t.Errorf("f.List(): -want, +got: \n%s\n", diff)
+	}
+
+	f.Forget("coolns", "coolpod")
+	if _, ok := f.Get("coolns", "coolpod"); ok {
+		t.Errorf("f.Get(...): want forgotten pod to be gone")
+	}
+}
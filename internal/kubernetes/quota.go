@@ -0,0 +1,143 @@
+package kubernetes
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	corev1 "k8s.io/api/core/v1"
+	kresource "k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+const defaultQuotaReconcileInterval = 5 * time.Minute
+
+// A NamespaceQuotaApplicator applies the configured ResourceQuota and
+// LimitRange to the remote namespaces AK creates, so that a single local
+// tenant cannot consume an entire remote cluster.
+type NamespaceQuotaApplicator struct {
+	cfg      NamespacesConfig
+	nodeName string
+	namer    remote.NamespaceNamer
+	interval time.Duration
+}
+
+// NewNamespaceQuotaApplicator returns a NamespaceQuotaApplicator that applies
+// quotas to the remote namespaces created on behalf of the supplied node.
+func NewNamespaceQuotaApplicator(cfg NamespacesConfig, nodeName string, n remote.NamespaceNamer) *NamespaceQuotaApplicator {
+	return &NamespaceQuotaApplicator{cfg: cfg, nodeName: nodeName, namer: n, interval: defaultQuotaReconcileInterval}
+}
+
+// Run the NamespaceQuotaApplicator, reconciling the quotas of all remote
+// namespaces in the supplied remote clusters at its configured interval until
+// the supplied context is done.
+func (a *NamespaceQuotaApplicator) Run(ctx context.Context, remotes []RemoteCluster) {
+	t := time.NewTicker(a.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			for _, rc := range remotes {
+				if err := a.Reconcile(ctx, rc.ClientApplicator); err != nil {
+					log.G(ctx).WithError(err).WithField("remote", rc.Name).Error("cannot reconcile remote namespace quotas")
+				}
+			}
+		}
+	}
+}
+
+// Reconcile the quotas of all remote namespaces created on behalf of our node
+// in the supplied remote cluster. Failing to apply the quota of one namespace
+// does not prevent the others from being reconciled; all such errors are
+// returned.
+func (a *NamespaceQuotaApplicator) Reconcile(ctx context.Context, c resource.ClientApplicator) error {
+	if !a.cfg.QuotaConfigured() {
+		return nil
+	}
+
+	l := &corev1.NamespaceList{}
+	if err := c.List(ctx, l, client.MatchingLabels{remote.LabelKeyNodeName: a.nodeName}); err != nil {
+		return errors.Wrap(err, "cannot list remote namespaces")
+	}
+
+	var errs []error
+	for _, ns := range l.Items {
+		lns, ok := ns.GetLabels()[remote.LabelKeyNamespace]
+		if !ok {
+			continue
+		}
+		if err := a.Apply(ctx, c, lns); err != nil {
+			errs = append(errs, errors.Wrapf(err, "cannot apply quota to remote namespace %q", ns.GetName()))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// Apply the quota configured for the supplied local namespace to its remote
+// namespace. A ResourceQuota or LimitRange that AK created but that is no
+// longer configured is deleted. Apply is a no-op if no quota is configured for
+// any namespace; AK does not manage remote quotas at all in that case.
+func (a *NamespaceQuotaApplicator) Apply(ctx context.Context, c resource.ClientApplicator, localNamespace string) error {
+	if !a.cfg.QuotaConfigured() {
+		return nil
+	}
+
+	q := a.cfg.QuotaFor(localNamespace)
+	o := remote.WithNamespaceNamer(a.namer)
+
+	rq := remote.ResourceQuota(a.nodeName, localNamespace, resourceList(q.ResourceQuota), o)
+	if err := a.applyOrDelete(ctx, c, rq, len(rq.Spec.Hard) > 0); err != nil {
+		return errors.Wrap(err, "cannot apply remote resource quota")
+	}
+
+	lri := corev1.LimitRangeItem{
+		Default:        resourceList(q.LimitRange.Default),
+		DefaultRequest: resourceList(q.LimitRange.DefaultRequest),
+		Min:            resourceList(q.LimitRange.Min),
+		Max:            resourceList(q.LimitRange.Max),
+	}
+	lr := remote.LimitRange(a.nodeName, localNamespace, lri, o)
+	configured := lri.Default != nil || lri.DefaultRequest != nil || lri.Min != nil || lri.Max != nil
+	return errors.Wrap(a.applyOrDelete(ctx, c, lr, configured), "cannot apply remote limit range")
+}
+
+// applyOrDelete applies the supplied object, or deletes it if it should not be
+// applied. Only an object labelled as created on behalf of our node is deleted.
+func (a *NamespaceQuotaApplicator) applyOrDelete(ctx context.Context, c resource.ClientApplicator, o resource.Object, apply bool) error {
+	if apply {
+		return c.Apply(ctx, o)
+	}
+
+	existing := o.DeepCopyObject().(resource.Object)
+	if err := c.Get(ctx, types.NamespacedName{Namespace: o.GetNamespace(), Name: o.GetName()}, existing); err != nil {
+		return resource.IgnoreNotFound(err)
+	}
+	if existing.GetLabels()[remote.LabelKeyNodeName] != a.nodeName {
+		return nil
+	}
+	return resource.IgnoreNotFound(c.Delete(ctx, existing))
+}
+
+// resourceList returns a ResourceList of the supplied quantities, or nil if
+// there are none. Quantities are assumed to have been validated.
+func resourceList(in map[string]string) corev1.ResourceList {
+	if len(in) == 0 {
+		return nil
+	}
+	rl := make(corev1.ResourceList, len(in))
+	for name, quantity := range in {
+		rl[corev1.ResourceName(name)] = kresource.MustParse(quantity)
+	}
+	return rl
+}
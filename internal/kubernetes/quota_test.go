@@ -0,0 +1,225 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	kresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+func TestNamespaceQuotaApplicatorApply(t *testing.T) {
+	errBoom := errors.New("boom")
+	nodeName := "coolnode"
+	ns := "coolns"
+
+	quota := NamespaceQuotaConfig{
+		ResourceQuota: map[string]string{"requests.cpu": "10"},
+		LimitRange:    LimitRangeConfig{Default: map[string]string{"cpu": "1"}},
+	}
+	rq := remote.ResourceQuota(nodeName, ns, corev1.ResourceList{"requests.cpu": kresource.MustParse("10")})
+	lr := remote.LimitRange(nodeName, ns, corev1.LimitRangeItem{Default: corev1.ResourceList{"cpu": kresource.MustParse("1")}})
+
+	type want struct {
+		applied []runtime.Object
+		deleted []runtime.Object
+		err     error
+	}
+	cases := map[string]struct {
+		reason   string
+		cfg      NamespacesConfig
+		a        resource.Applicator
+		existing map[string]string
+		want     want
+	}{
+		"ApplyError": {
+			reason: "Errors applying the resource quota should be returned",
+			cfg:    NamespacesConfig{Quota: quota},
+			a: resource.ApplyFn(func(_ context.Context, _ runtime.Object, _ ...resource.ApplyOption) error {
+				return errBoom
+			}),
+			want: want{
+				err: errors.Wrap(errBoom, "cannot apply remote resource quota"),
+			},
+		},
+		"Default": {
+			reason: "The default quota should be applied to namespaces without an override",
+			cfg:    NamespacesConfig{Quota: quota},
+			want: want{
+				applied: []runtime.Object{rq, lr},
+			},
+		},
+		"Override": {
+			reason: "An override should replace the default quota",
+			cfg: NamespacesConfig{
+				Quota: quota,
+				Overrides: map[string]NamespaceQuotaConfig{
+					ns: {LimitRange: LimitRangeConfig{Default: map[string]string{"cpu": "1"}}},
+				},
+			},
+			existing: map[string]string{remote.LabelKeyNodeName: nodeName, remote.LabelKeyNamespace: ns},
+			want: want{
+				applied: []runtime.Object{lr},
+				deleted: []runtime.Object{remote.ResourceQuota(nodeName, ns, nil)},
+			},
+		},
+		"Exempt": {
+			reason: "Any existing quota should be deleted when a namespace is exempted from quota",
+			cfg: NamespacesConfig{
+				Quota:     quota,
+				Overrides: map[string]NamespaceQuotaConfig{ns: {}},
+			},
+			existing: map[string]string{remote.LabelKeyNodeName: nodeName, remote.LabelKeyNamespace: ns},
+			want: want{
+				deleted: []runtime.Object{remote.ResourceQuota(nodeName, ns, nil), remote.LimitRange(nodeName, ns, corev1.LimitRangeItem{})},
+			},
+		},
+		"ExemptNotCreatedByAK": {
+			reason: "An existing quota should not be deleted unless it was created on behalf of our node",
+			cfg: NamespacesConfig{
+				Quota:     quota,
+				Overrides: map[string]NamespaceQuotaConfig{ns: {}},
+			},
+			existing: map[string]string{remote.LabelKeyNodeName: "other"},
+		},
+		"ExemptNotFound": {
+			reason: "Nothing should be deleted when no quota exists",
+			cfg: NamespacesConfig{
+				Quota:     quota,
+				Overrides: map[string]NamespaceQuotaConfig{ns: {}},
+			},
+		},
+		"Unconfigured": {
+			reason:   "Nothing should be applied or deleted when no quota is configured",
+			cfg:      NamespacesConfig{},
+			existing: map[string]string{remote.LabelKeyNodeName: nodeName, remote.LabelKeyNamespace: ns},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var applied, deleted []runtime.Object
+			var a resource.Applicator = resource.ApplyFn(func(_ context.Context, obj runtime.Object, _ ...resource.ApplyOption) error {
+				applied = append(applied, obj)
+				return nil
+			})
+			if tc.a != nil {
+				a = tc.a
+			}
+			c := &test.MockClient{
+				MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+					if tc.existing == nil {
+						return kerrors.NewNotFound(schema.GroupResource{}, "")
+					}
+					obj.(metav1.Object).SetLabels(tc.existing)
+					return nil
+				},
+				MockDelete: func(_ context.Context, obj runtime.Object, _ ...client.DeleteOption) error {
+					deleted = append(deleted, obj)
+					return nil
+				},
+			}
+
+			qa := NewNamespaceQuotaApplicator(tc.cfg, nodeName, remote.HashNamespaceNamer)
+			err := qa.Apply(context.Background(), resource.ClientApplicator{Client: c, Applicator: a}, ns)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nqa.Apply(...): -want error, +got error: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.applied, applied); diff != "" {
+				t.Errorf("\n%s\nqa.Apply(...): -want applied, +got applied: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.deleted, deleted); diff != "" {
+				t.Errorf("\n%s\nqa.Apply(...): -want deleted, +got deleted: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNamespaceQuotaApplicatorReconcile(t *testing.T) {
+	errBoom := errors.New("boom")
+	nodeName := "coolnode"
+
+	quota := NamespaceQuotaConfig{ResourceQuota: map[string]string{"requests.cpu": "10"}}
+	namespace := func(local string) corev1.Namespace {
+		return *remote.Namespace(nodeName, local)
+	}
+	rq := func(local string) runtime.Object {
+		return remote.ResourceQuota(nodeName, local, corev1.ResourceList{"requests.cpu": kresource.MustParse("10")})
+	}
+
+	type want struct {
+		applied []runtime.Object
+		err     error
+	}
+	cases := map[string]struct {
+		reason string
+		items  []corev1.Namespace
+		fail   string
+		want   want
+	}{
+		"Reconciled": {
+			reason: "The quotas of all remote namespaces should be applied",
+			items:  []corev1.Namespace{namespace("a"), namespace("b")},
+			want: want{
+				applied: []runtime.Object{rq("a"), rq("b")},
+			},
+		},
+		"ApplyError": {
+			reason: "Failing to apply the quota of one remote namespace should not prevent the others being applied",
+			items:  []corev1.Namespace{namespace("a"), namespace("b")},
+			fail:   remote.NamespaceName(nodeName, "a"),
+			want: want{
+				applied: []runtime.Object{rq("b")},
+				err: utilerrors.NewAggregate([]error{
+					errors.Wrapf(errors.Wrap(errBoom, "cannot apply remote resource quota"), "cannot apply quota to remote namespace %q", remote.NamespaceName(nodeName, "a")),
+				}),
+			},
+		},
+		"NotCreatedByAK": {
+			reason: "Remote namespaces without a local namespace label should be ignored",
+			items:  []corev1.Namespace{{}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var applied []runtime.Object
+			a := resource.ApplyFn(func(_ context.Context, obj runtime.Object, _ ...resource.ApplyOption) error {
+				if m, ok := obj.(metav1.Object); ok && m.GetNamespace() == tc.fail {
+					return errBoom
+				}
+				applied = append(applied, obj)
+				return nil
+			})
+			c := &test.MockClient{
+				MockList: func(_ context.Context, obj runtime.Object, _ ...client.ListOption) error {
+					obj.(*corev1.NamespaceList).Items = tc.items
+					return nil
+				},
+				MockGet: test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{}, "")),
+			}
+
+			qa := NewNamespaceQuotaApplicator(NamespacesConfig{Quota: quota}, nodeName, remote.HashNamespaceNamer)
+			err := qa.Reconcile(context.Background(), resource.ClientApplicator{Client: c, Applicator: a})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nqa.Reconcile(...): -want error, +got error: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.applied, applied); diff != "" {
+				t.Errorf("\n%s\nqa.Reconcile(...): -want applied, +got applied: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
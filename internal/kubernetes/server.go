@@ -0,0 +1,177 @@
+package kubernetes
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/virtual-kubelet/virtual-kubelet/node/api"
+	"k8s.io/apimachinery/pkg/types"
+	remotecommandconsts "k8s.io/apimachinery/pkg/util/remotecommand"
+	"k8s.io/client-go/tools/remotecommand"
+	portforwardserver "k8s.io/kubernetes/pkg/kubelet/server/portforward"
+	remotecommandserver "k8s.io/kubernetes/pkg/kubelet/server/remotecommand"
+)
+
+// StreamTimeouts configure the streaming (i.e. exec, attach, and port forward)
+// endpoints of the kubelet pod API.
+type StreamTimeouts struct {
+	// Idle is how long a streaming connection may be idle before it's closed.
+	Idle time.Duration
+
+	// Creation is how long to wait for a client to create the streams of a
+	// streaming connection.
+	Creation time.Duration
+}
+
+// A ContainerAttachHandlerFunc attaches the supplied streams to the supplied
+// container of the supplied pod.
+type ContainerAttachHandlerFunc func(ctx context.Context, namespace, podName, containerName string, attach api.AttachIO) error
+
+// A PortForwardHandlerFunc forwards the supplied stream to the supplied port of
+// the supplied pod.
+type PortForwardHandlerFunc func(ctx context.Context, namespace, podName string, port int32, stream io.ReadWriteCloser) error
+
+// NewPodHandler returns an http.Handler that serves the kubelet pod API backed
+// by the supplied Provider, using the supplied stream timeouts.
+//
+// NOTE(negz): The version of virtual-kubelet we depend on only serves the
+// exec, logs, running pods, and stats summary endpoints of the kubelet API. We
+// serve the endpoints it doesn't (i.e. attach and port forward) ourselves, and
+// delegate the rest.
+func NewPodHandler(p *Provider, t StreamTimeouts) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/attach/", HandleContainerAttach(p.AttachToContainer, t))
+	mux.Handle("/portForward/", HandlePortForward(p.PortForward, t))
+	mux.Handle("/", api.PodHandler(api.PodHandlerConfig{
+		RunInContainer:        p.RunInContainer,
+		GetContainerLogs:      p.GetContainerLogs,
+		GetPods:               p.GetPods,
+		GetStatsSummary:       p.GetStatsSummary,
+		StreamIdleTimeout:     t.Idle,
+		StreamCreationTimeout: t.Creation,
+	}, true))
+	return mux
+}
+
+// HandleContainerAttach returns an http.HandlerFunc that serves attach requests
+// to /attach/{namespace}/{pod}/{container} using the supplied handler.
+func HandleContainerAttach(h ContainerAttachHandlerFunc, t StreamTimeouts) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, ok := pathParams(r.URL.Path, "/attach/", 3)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		o, err := remotecommandserver.NewOptions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		a := &attacher{ctx: r.Context(), h: h, namespace: params[0]}
+		remotecommandserver.ServeAttach(w, r, a, params[1], "", params[2], o, t.Idle, t.Creation, remotecommandconsts.SupportedStreamingProtocols)
+	}
+}
+
+// An attacher adapts a ContainerAttachHandlerFunc to the interface expected by
+// the kubelet's attach server, which is unaware of namespaces.
+type attacher struct {
+	ctx       context.Context
+	h         ContainerAttachHandlerFunc
+	namespace string
+}
+
+func (a *attacher) AttachContainer(podName string, _ types.UID, container string, in io.Reader, out, err io.WriteCloser, tty bool, resize <-chan remotecommand.TerminalSize) error {
+	ctx, cancel := context.WithCancel(a.ctx)
+	defer cancel()
+
+	aio := &attachIO{stdin: in, stdout: out, stderr: err, tty: tty}
+	if tty {
+		aio.resize = make(chan api.TermSize)
+		go func() {
+			for {
+				select {
+				case s, ok := <-resize:
+					if !ok {
+						return
+					}
+					select {
+					case aio.resize <- api.TermSize{Width: s.Width, Height: s.Height}:
+					case <-ctx.Done():
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	return a.h(ctx, a.namespace, podName, container, aio)
+}
+
+// An attachIO is an api.AttachIO.
+type attachIO struct {
+	stdin  io.Reader
+	stdout io.WriteCloser
+	stderr io.WriteCloser
+	tty    bool
+	resize chan api.TermSize
+}
+
+func (a *attachIO) Stdin() io.Reader            { return a.stdin }
+func (a *attachIO) Stdout() io.WriteCloser      { return a.stdout }
+func (a *attachIO) Stderr() io.WriteCloser      { return a.stderr }
+func (a *attachIO) TTY() bool                   { return a.tty }
+func (a *attachIO) Resize() <-chan api.TermSize { return a.resize }
+
+// HandlePortForward returns an http.HandlerFunc that serves port forward
+// requests to /portForward/{namespace}/{pod} using the supplied handler.
+func HandlePortForward(h PortForwardHandlerFunc, t StreamTimeouts) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, ok := pathParams(r.URL.Path, "/portForward/", 2)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		o, err := portforwardserver.NewV4Options(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		pf := &portForwarder{ctx: r.Context(), h: h, namespace: params[0]}
+		portforwardserver.ServePortForward(w, r, pf, params[1], "", o, t.Idle, t.Creation, portforwardserver.SupportedProtocols)
+	}
+}
+
+// A portForwarder adapts a PortForwardHandlerFunc to the interface expected by
+// the kubelet's port forward server, which is unaware of namespaces.
+type portForwarder struct {
+	ctx       context.Context
+	h         PortForwardHandlerFunc
+	namespace string
+}
+
+func (f *portForwarder) PortForward(podName string, _ types.UID, port int32, stream io.ReadWriteCloser) error {
+	return f.h(f.ctx, f.namespace, podName, port, stream)
+}
+
+// pathParams returns the n non-empty path segments that follow the supplied
+// prefix, or false if the path does not consist of exactly n such segments.
+func pathParams(path, prefix string, n int) ([]string, bool) {
+	if !strings.HasPrefix(path, prefix) {
+		return nil, false
+	}
+	params := strings.Split(strings.TrimSuffix(strings.TrimPrefix(path, prefix), "/"), "/")
+	if len(params) != n {
+		return nil, false
+	}
+	for _, p := range params {
+		if p == "" {
+			return nil, false
+		}
+	}
+	return params, true
+}
@@ -0,0 +1,301 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/virtual-kubelet/virtual-kubelet/node/api"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+type rwc struct {
+	io.ReadWriter
+}
+
+func (rwc) Close() error { return nil }
+
+func TestPathParams(t *testing.T) {
+	type args struct {
+		path   string
+		prefix string
+		n      int
+	}
+	type want struct {
+		params []string
+		ok     bool
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Params": {
+			reason: "The path segments following the prefix should be returned",
+			args:   args{path: "/portForward/coolns/coolpod", prefix: "/portForward/", n: 2},
+			want:   want{params: []string{"coolns", "coolpod"}, ok: true},
+		},
+		"TrailingSlash": {
+			reason: "A trailing slash should be ignored",
+			args:   args{path: "/portForward/coolns/coolpod/", prefix: "/portForward/", n: 2},
+			want:   want{params: []string{"coolns", "coolpod"}, ok: true},
+		},
+		"WrongPrefix": {
+			reason: "A path without the prefix should not match",
+			args:   args{path: "/exec/coolns/coolpod", prefix: "/portForward/", n: 2},
+			want:   want{ok: false},
+		},
+		"TooFew": {
+			reason: "A path with too few segments should not match",
+			args:   args{path: "/portForward/coolns", prefix: "/portForward/", n: 2},
+			want:   want{ok: false},
+		},
+		"TooMany": {
+			reason: "A path with too many segments should not match",
+			args:   args{path: "/portForward/coolns/coolpod/wat", prefix: "/portForward/", n: 2},
+			want:   want{ok: false},
+		},
+		"Empty": {
+			reason: "A path with an empty segment should not match",
+			args:   args{path: "/portForward//coolpod", prefix: "/portForward/", n: 2},
+			want:   want{ok: false},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			params, ok := pathParams(tc.args.path, tc.args.prefix, tc.args.n)
+			if diff := cmp.Diff(tc.want.params, params); diff != "" {
+				t.Errorf("\n%s\npathParams(...): -want, +got: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.ok, ok); diff != "" {
+				t.Errorf("\n%s\npathParams(...): -want ok, +got ok: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNewPodHandler(t *testing.T) {
+	h := NewPodHandler(&Provider{nodeName: "coolnode"}, StreamTimeouts{})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stats/summary", nil))
+	if diff := cmp.Diff(http.StatusOK, w.Code); diff != "" {
+		t.Fatalf("h.ServeHTTP(...): -want status, +got status: \n%s\n", diff)
+	}
+
+	got := &stats.Summary{}
+	if err := json.Unmarshal(w.Body.Bytes(), got); err != nil {
+		t.Fatalf("json.Unmarshal(...): %s", err)
+	}
+	if diff := cmp.Diff("coolnode", got.Node.NodeName); diff != "" {
+		t.Errorf("h.ServeHTTP(...): -want node name, +got node name: \n%s\n", diff)
+	}
+}
+
+func TestHandleContainerAttach(t *testing.T) {
+	called := false
+	h := HandleContainerAttach(func(_ context.Context, _, _, _ string, _ api.AttachIO) error {
+		called = true
+		return nil
+	}, StreamTimeouts{})
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPost, "/attach/coolns/coolpod", nil))
+	if diff := cmp.Diff(http.StatusNotFound, w.Code); diff != "" {
+		t.Errorf("h(...): -want status, +got status: \n%s\n", diff)
+	}
+	if called {
+		t.Errorf("h(...): want malformed request not to be attached")
+	}
+}
+
+func TestAttacher(t *testing.T) {
+	type call struct {
+		Namespace string
+		Pod       string
+		Container string
+		TTY       bool
+		Size      api.TermSize
+	}
+	want := call{Namespace: "coolns", Pod: "coolpod", Container: "coolctr", TTY: true, Size: api.TermSize{Width: 80, Height: 24}}
+	stdin := &bytes.Buffer{}
+	stdout := rwc{&bytes.Buffer{}}
+
+	resize := make(chan remotecommand.TerminalSize, 1)
+	resize <- remotecommand.TerminalSize{Width: 80, Height: 24}
+
+	var got call
+	var gotStdin io.Reader
+	var gotStdout, gotStderr io.WriteCloser
+	a := &attacher{
+		ctx:       context.Background(),
+		namespace: "coolns",
+		h: func(_ context.Context, namespace, podName, containerName string, attach api.AttachIO) error {
+			got = call{Namespace: namespace, Pod: podName, Container: containerName, TTY: attach.TTY(), Size: <-attach.Resize()}
+			gotStdin, gotStdout, gotStderr = attach.Stdin(), attach.Stdout(), attach.Stderr()
+			return nil
+		},
+	}
+
+	if err := a.AttachContainer("coolpod", types.UID("cool-uid"), "coolctr", stdin, stdout, nil, true, resize); err != nil {
+		t.Fatalf("a.AttachContainer(...): %s", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("a.AttachContainer(...): -want, +got: \n%s\n", diff)
+	}
+	if gotStdin != stdin || gotStdout != stdout || gotStderr != nil {
+		t.Errorf("a.AttachContainer(...): want supplied streams to be attached")
+	}
+}
+
+func TestHandlePortForward(t *testing.T) {
+	called := false
+	h := HandlePortForward(func(_ context.Context, _, _ string, _ int32, _ io.ReadWriteCloser) error {
+		called = true
+		return nil
+	}, StreamTimeouts{})
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPost, "/portForward/coolns", nil))
+	if diff := cmp.Diff(http.StatusNotFound, w.Code); diff != "" {
+		t.Errorf("h(...): -want status, +got status: \n%s\n", diff)
+	}
+	if called {
+		t.Errorf("h(...): want malformed request not to be forwarded")
+	}
+}
+
+func TestPortForwarder(t *testing.T) {
+	type call struct {
+		Namespace string
+		Pod       string
+		Port      int32
+	}
+	want := call{Namespace: "coolns", Pod: "coolpod", Port: 8080}
+	stream := rwc{&bytes.Buffer{}}
+
+	var got call
+	var gotStream io.ReadWriteCloser
+	pf := &portForwarder{
+		ctx:       context.Background(),
+		namespace: "coolns",
+		h: func(_ context.Context, namespace, podName string, port int32, s io.ReadWriteCloser) error {
+			got = call{Namespace: namespace, Pod: podName, Port: port}
+			gotStream = s
+			return nil
+		},
+	}
+
+	if err := pf.PortForward("coolpod", types.UID("cool-uid"), 8080, stream); err != nil {
+		t.Fatalf("pf.PortForward(...): %s", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("pf.PortForward(...): -want, +got: \n%s\n", diff)
+	}
+	if gotStream != stream {
+		t.Errorf("pf.PortForward(...): want supplied stream to be forwarded")
+	}
+}
+
+func TestProviderStreams(t *testing.T) {
+	nodeName := "coolnode"
+	ns := "coolns"
+	pod := "coolpod"
+
+	type request struct {
+		Method    string
+		Path      string
+		Container string
+	}
+	cases := map[string]struct {
+		reason string
+		stream func(ctx context.Context, p *Provider) error
+		want   request
+	}{
+		"Attach": {
+			reason: "Attaches should be made to the attach subresource of the remote pod",
+			stream: func(ctx context.Context, p *Provider) error {
+				return p.AttachToContainer(ctx, ns, pod, "coolctr", &attachIO{stdout: rwc{&bytes.Buffer{}}})
+			},
+			want: request{
+				Method:    http.MethodPost,
+				Path:      "/api/v1/namespaces/" + remote.NamespaceName(nodeName, ns) + "/pods/" + pod + "/attach",
+				Container: "coolctr",
+			},
+		},
+		"Exec": {
+			reason: "Commands should be executed via the exec subresource of the remote pod",
+			stream: func(ctx context.Context, p *Provider) error {
+				return p.RunInContainer(ctx, ns, pod, "coolctr", []string{"sh"}, &attachIO{stdout: rwc{&bytes.Buffer{}}})
+			},
+			want: request{
+				Method:    http.MethodPost,
+				Path:      "/api/v1/namespaces/" + remote.NamespaceName(nodeName, ns) + "/pods/" + pod + "/exec",
+				Container: "coolctr",
+			},
+		},
+		"PortForward": {
+			reason: "Port forwards should be made to the portforward subresource of the remote pod",
+			stream: func(ctx context.Context, p *Provider) error {
+				return p.PortForward(ctx, ns, pod, 8080, rwc{&bytes.Buffer{}})
+			},
+			want: request{
+				Method: http.MethodPost,
+				Path:   "/api/v1/namespaces/" + remote.NamespaceName(nodeName, ns) + "/pods/" + pod + "/portforward",
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got request
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = request{Method: r.Method, Path: r.URL.Path, Container: r.URL.Query().Get("container")}
+				http.Error(w, "nope", http.StatusForbidden)
+			}))
+			defer srv.Close()
+
+			cfg := &rest.Config{Host: srv.URL}
+			cs, err := kubernetes.NewForConfig(cfg)
+			if err != nil {
+				t.Fatalf("kubernetes.NewForConfig(...): %s", err)
+			}
+			p := &Provider{
+				nodeName: nodeName,
+				namer:    remote.HashNamespaceNamer,
+				remotes: []RemoteCluster{{
+					Name: "cool",
+					Client: Client{
+						ClientApplicator: resource.ClientApplicator{Client: &test.MockClient{MockGet: test.NewMockGetFn(nil)}},
+						Interface:        cs,
+						Config:           cfg,
+					},
+				}},
+			}
+
+			// The remote API server refuses to upgrade the connection, so
+			// we expect an error.
+			if err := tc.stream(context.Background(), p); err == nil {
+				t.Errorf("\n%s\nstream(...): want error, got nil", tc.reason)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nstream(...): -want request, +got request: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
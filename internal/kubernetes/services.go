@@ -0,0 +1,208 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kcache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+// A ServiceMirror mirrors local services to the corresponding namespaces of
+// remote clusters, allowing remote pods to reach local services using their
+// (unqualified) local names.
+type ServiceMirror struct {
+	local    client.Reader
+	remotes  []resource.ClientApplicator
+	nodeName string
+	namer    remote.NamespaceNamer
+	mode     ServiceMirrorMode
+	domain   string
+}
+
+// A ServiceMirrorOption configures a ServiceMirror.
+type ServiceMirrorOption func(*ServiceMirror)
+
+// WithMirrorNamespaceNamer configures how a ServiceMirror names the remote
+// namespaces it mirrors services to.
+func WithMirrorNamespaceNamer(n remote.NamespaceNamer) ServiceMirrorOption {
+	return func(m *ServiceMirror) {
+		m.namer = n
+	}
+}
+
+// WithMirrorMode configures how a ServiceMirror mirrors services. The supplied
+// domain is used to derive external names when mirroring services as
+// ExternalName services.
+func WithMirrorMode(mode ServiceMirrorMode, domain string) ServiceMirrorOption {
+	return func(m *ServiceMirror) {
+		m.mode = mode
+		m.domain = domain
+	}
+}
+
+// NewServiceMirror returns a ServiceMirror that mirrors local services to the
+// supplied remote API servers. Services are mirrored along with their
+// endpoints by default.
+func NewServiceMirror(local client.Reader, remotes []resource.ClientApplicator, nodeName string, o ...ServiceMirrorOption) *ServiceMirror {
+	m := &ServiceMirror{
+		local:    local,
+		remotes:  remotes,
+		nodeName: nodeName,
+		namer:    remote.HashNamespaceNamer,
+		mode:     ServiceMirrorModeEndpoints,
+	}
+	for _, fn := range o {
+		fn(m)
+	}
+	return m
+}
+
+// Start mirroring services when the supplied informers observe that they, or
+// their endpoints, have been created, updated, or deleted.
+func (m *ServiceMirror) Start(ctx context.Context, i cache.Informers) error {
+	for _, o := range []runtime.Object{&corev1.Service{}, &corev1.Endpoints{}} {
+		inf, err := i.GetInformer(ctx, o)
+		if err != nil {
+			return errors.Wrap(err, "cannot get informer")
+		}
+		inf.AddEventHandler(kcache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				m.sync(ctx, obj)
+			},
+			UpdateFunc: func(_, obj interface{}) {
+				m.sync(ctx, obj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				m.sync(ctx, obj)
+			},
+		})
+	}
+	return nil
+}
+
+func (m *ServiceMirror) sync(ctx context.Context, obj interface{}) {
+	key, err := kcache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	ns, name, err := kcache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return
+	}
+	if err := m.Sync(ctx, types.NamespacedName{Namespace: ns, Name: name}); err != nil {
+		log.G(ctx).WithError(err).Error("cannot mirror service")
+	}
+}
+
+// Sync the supplied local service (and its endpoints) to the remote API
+// servers, or remove it from them if it no longer exists locally.
+func (m *ServiceMirror) Sync(ctx context.Context, nn types.NamespacedName) error {
+	lsvc := &corev1.Service{}
+	err := m.local.Get(ctx, nn, lsvc)
+	if kerrors.IsNotFound(err) {
+		return m.delete(ctx, nn)
+	}
+	if err != nil {
+		return errors.Wrap(err, "cannot get local service")
+	}
+
+	objs := make([]runtime.Object, 0, 2)
+
+	rsvc := lsvc.DeepCopy()
+	en := ""
+	if m.mode == ServiceMirrorModeExternalName {
+		en = fmt.Sprintf("%s.%s.svc.%s", nn.Name, nn.Namespace, m.domain)
+	}
+	remote.PrepareService(rsvc, en)
+	remote.PrepareObject(m.nodeName, rsvc, remote.WithNamespaceNamer(m.namer))
+	objs = append(objs, rsvc)
+
+	if m.mode == ServiceMirrorModeEndpoints {
+		lep := &corev1.Endpoints{}
+		if err := m.local.Get(ctx, nn, lep); resource.IgnoreNotFound(err) != nil {
+			return errors.Wrap(err, "cannot get local endpoints")
+		}
+		// A service's endpoints may not exist yet. We'll mirror them when
+		// they're created.
+		if lep.GetName() != "" {
+			rep := lep.DeepCopy()
+			remote.PrepareEndpoints(rep)
+			remote.PrepareObject(m.nodeName, rep, remote.WithNamespaceNamer(m.namer))
+			objs = append(objs, rep)
+		}
+	}
+
+	// NOTE(negz): A service is mirrored to a remote namespace that was created
+	// after the service was last synced when the local informer next resyncs.
+	for _, rc := range m.remotes {
+		for _, o := range objs {
+			// The remote namespace will not exist in remote clusters that are
+			// not running any pods from the local namespace. There's no need
+			// to mirror our service to those clusters.
+			err := rc.Apply(ctx, o.DeepCopyObject(), preserveClusterIP)
+			if kerrors.IsNotFound(errors.Cause(err)) {
+				break
+			}
+			if err != nil {
+				return errors.Wrap(err, "cannot apply remote mirrored service")
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *ServiceMirror) delete(ctx context.Context, nn types.NamespacedName) error {
+	rnn := types.NamespacedName{Namespace: m.namer.NamespaceName(m.nodeName, nn.Namespace), Name: nn.Name}
+	for _, rc := range m.remotes {
+		for _, o := range []runtime.Object{&corev1.Service{}, &corev1.Endpoints{}} {
+			err := rc.Get(ctx, rnn, o)
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return errors.Wrap(err, "cannot get remote mirrored service")
+			}
+
+			// Don't delete remote services we didn't create.
+			if o.(metav1.Object).GetLabels()[remote.LabelKeyNodeName] != m.nodeName {
+				continue
+			}
+			if err := rc.Delete(ctx, o); resource.IgnoreNotFound(err) != nil {
+				return errors.Wrap(err, "cannot delete remote mirrored service")
+			}
+		}
+	}
+	return nil
+}
+
+// preserveClusterIP preserves the cluster IP that the remote API server
+// allocated to a mirrored service. A service's cluster IP cannot be changed
+// once it has been allocated.
+func preserveClusterIP(_ context.Context, current, desired runtime.Object) error {
+	c, ok := current.(*corev1.Service)
+	if !ok {
+		return nil
+	}
+	d, ok := desired.(*corev1.Service)
+	if !ok {
+		return nil
+	}
+	if d.Spec.Type != corev1.ServiceTypeExternalName && d.Spec.ClusterIP == "" {
+		d.Spec.ClusterIP = c.Spec.ClusterIP
+	}
+	return nil
+}
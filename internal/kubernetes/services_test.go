@@ -0,0 +1,193 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+func TestServiceMirrorSync(t *testing.T) {
+	errBoom := errors.New("boom")
+	nodeName := "coolnode"
+	ns := "coolns"
+	svcName := "coolsvc"
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: svcName},
+		Spec: corev1.ServiceSpec{
+			Type:      corev1.ServiceTypeNodePort,
+			ClusterIP: "10.0.0.1",
+			Selector:  map[string]string{"app": "cool"},
+			Ports:     []corev1.ServicePort{{Name: "http", Port: 80, NodePort: 30080}},
+		},
+	}
+	ep := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: svcName},
+		Subsets: []corev1.EndpointSubset{{
+			Addresses: []corev1.EndpointAddress{{
+				IP:        "192.168.0.1",
+				TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "coolpod"},
+			}},
+			Ports: []corev1.EndpointPort{{Name: "http", Port: 8080}},
+		}},
+	}
+	local := &test.MockClient{
+		MockGet: func(_ context.Context, _ client.ObjectKey, obj runtime.Object) error {
+			switch o := obj.(type) {
+			case *corev1.Service:
+				svc.DeepCopyInto(o)
+			case *corev1.Endpoints:
+				ep.DeepCopyInto(o)
+			}
+			return nil
+		},
+	}
+	labels := map[string]string{
+		remote.LabelKeyNodeName:  nodeName,
+		remote.LabelKeyNamespace: ns,
+	}
+	rmeta := metav1.ObjectMeta{Namespace: remote.NamespaceName(nodeName, ns), Name: svcName, Labels: labels}
+
+	type want struct {
+		applied []runtime.Object
+		deleted []runtime.Object
+		err     error
+	}
+	cases := map[string]struct {
+		reason string
+		c      client.Reader
+		rc     client.Client
+		a      resource.Applicator
+		o      []ServiceMirrorOption
+		want   want
+	}{
+		"GetServiceError": {
+			reason: "Errors getting the local service should be returned",
+			c:      &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+			want: want{
+				err: errors.Wrap(errBoom, "cannot get local service"),
+			},
+		},
+		"ServiceDeleted": {
+			reason: "Remote mirrors of services that no longer exist locally should be deleted",
+			c:      &test.MockClient{MockGet: test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{}, svcName))},
+			rc: &test.MockClient{
+				MockGet: test.NewMockGetFn(nil, func(obj runtime.Object) error {
+					obj.(metav1.Object).SetLabels(labels)
+					return nil
+				}),
+			},
+			want: want{
+				deleted: []runtime.Object{
+					&corev1.Service{ObjectMeta: metav1.ObjectMeta{Labels: labels}},
+					&corev1.Endpoints{ObjectMeta: metav1.ObjectMeta{Labels: labels}},
+				},
+			},
+		},
+		"RemoteNamespaceNotFound": {
+			reason: "Services should not be mirrored to remote clusters that lack the remote namespace",
+			c:      local,
+			a: resource.ApplyFn(func(_ context.Context, _ runtime.Object, _ ...resource.ApplyOption) error {
+				return errors.Wrap(kerrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, ns), "cannot create object")
+			}),
+		},
+		"ApplyError": {
+			reason: "Errors applying the remote service should be returned",
+			c:      local,
+			a: resource.ApplyFn(func(_ context.Context, _ runtime.Object, _ ...resource.ApplyOption) error {
+				return errBoom
+			}),
+			want: want{
+				err: errors.Wrap(errBoom, "cannot apply remote mirrored service"),
+			},
+		},
+		"Endpoints": {
+			reason: "Services should be mirrored without a selector, along with their endpoints",
+			c:      local,
+			want: want{
+				applied: []runtime.Object{
+					&corev1.Service{
+						ObjectMeta: rmeta,
+						Spec: corev1.ServiceSpec{
+							Type:  corev1.ServiceTypeClusterIP,
+							Ports: []corev1.ServicePort{{Name: "http", Port: 80}},
+						},
+					},
+					&corev1.Endpoints{
+						ObjectMeta: rmeta,
+						Subsets: []corev1.EndpointSubset{{
+							Addresses: []corev1.EndpointAddress{{IP: "192.168.0.1"}},
+							Ports:     []corev1.EndpointPort{{Name: "http", Port: 8080}},
+						}},
+					},
+				},
+			},
+		},
+		"ExternalName": {
+			reason: "Services should be mirrored as ExternalName services when so configured",
+			c:      local,
+			o:      []ServiceMirrorOption{WithMirrorMode(ServiceMirrorModeExternalName, "example.org")},
+			want: want{
+				applied: []runtime.Object{
+					&corev1.Service{
+						ObjectMeta: rmeta,
+						Spec: corev1.ServiceSpec{
+							Type:         corev1.ServiceTypeExternalName,
+							ExternalName: "coolsvc.coolns.svc.example.org",
+							Ports:        []corev1.ServicePort{{Name: "http", Port: 80}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var applied, deleted []runtime.Object
+			var a resource.Applicator = resource.ApplyFn(func(_ context.Context, obj runtime.Object, _ ...resource.ApplyOption) error {
+				applied = append(applied, obj)
+				return nil
+			})
+			if tc.a != nil {
+				a = tc.a
+			}
+			rc := tc.rc
+			if rc == nil {
+				rc = &test.MockClient{}
+			}
+			if mc, ok := rc.(*test.MockClient); ok {
+				mc.MockDelete = func(_ context.Context, obj runtime.Object, _ ...client.DeleteOption) error {
+					deleted = append(deleted, obj)
+					return nil
+				}
+			}
+
+			m := NewServiceMirror(tc.c, []resource.ClientApplicator{{Client: rc, Applicator: a}}, nodeName, tc.o...)
+			err := m.Sync(context.Background(), types.NamespacedName{Namespace: ns, Name: svcName})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nm.Sync(...): -want error, +got error: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.applied, applied); diff != "" {
+				t.Errorf("\n%s\nm.Sync(...): -want applied, +got applied: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.deleted, deleted); diff != "" {
+				t.Errorf("\n%s\nm.Sync(...): -want deleted, +got deleted: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
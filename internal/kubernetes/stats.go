@@ -0,0 +1,78 @@
+package kubernetes
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+// FilterPodStats returns the stats of the supplied remote pods from the
+// supplied summary, which is presumed to have been produced by the kubelet of
+// the remote node the pods are running on. The returned stats refer to the
+// local pods that correspond to the supplied remote pods.
+func FilterPodStats(s *stats.Summary, pods []corev1.Pod) []stats.PodStats {
+	want := make(map[types.NamespacedName]*corev1.Pod, len(pods))
+	for i := range pods {
+		want[types.NamespacedName{Namespace: pods[i].GetNamespace(), Name: pods[i].GetName()}] = &pods[i]
+	}
+
+	filtered := make([]stats.PodStats, 0, len(pods))
+	for _, ps := range s.Pods {
+		rmt, ok := want[types.NamespacedName{Namespace: ps.PodRef.Namespace, Name: ps.PodRef.Name}]
+		if !ok {
+			continue
+		}
+		lcl := rmt.DeepCopy()
+		remote.RecoverObjectMeta(lcl)
+
+		// NOTE(negz): RecoverObjectMeta clears the remote pod's UID. We
+		// don't know the local pod's UID, but consumers of the summary API
+		// like the metrics-server identify pods by namespace and name.
+		ps.PodRef = stats.PodReference{Namespace: lcl.GetNamespace(), Name: lcl.GetName()}
+		filtered = append(filtered, ps)
+	}
+
+	return filtered
+}
+
+// SummarizeNode returns stats for the supplied node, which is presumed to be
+// running the supplied pods. The node's CPU and memory usage is the sum of the
+// CPU and memory usage of its pods.
+func SummarizeNode(nodeName string, pods []stats.PodStats) stats.NodeStats {
+	now := metav1.Now()
+	var cores, cpuSeconds, workingSet, usage uint64
+	for _, ps := range pods {
+		if c := ps.CPU; c != nil {
+			cores += derefUint64(c.UsageNanoCores)
+			cpuSeconds += derefUint64(c.UsageCoreNanoSeconds)
+		}
+		if m := ps.Memory; m != nil {
+			workingSet += derefUint64(m.WorkingSetBytes)
+			usage += derefUint64(m.UsageBytes)
+		}
+	}
+
+	return stats.NodeStats{
+		NodeName: nodeName,
+		CPU: &stats.CPUStats{
+			Time:                 now,
+			UsageNanoCores:       &cores,
+			UsageCoreNanoSeconds: &cpuSeconds,
+		},
+		Memory: &stats.MemoryStats{
+			Time:            now,
+			WorkingSetBytes: &workingSet,
+			UsageBytes:      &usage,
+		},
+	}
+}
+
+func derefUint64(i *uint64) uint64 {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
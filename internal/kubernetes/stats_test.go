@@ -0,0 +1,79 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+func TestFilterPodStats(t *testing.T) {
+	nodeName := "coolnode"
+	ns := "coolns"
+	remoteNs := remote.NamespaceName(nodeName, ns)
+	cores := uint64(42)
+
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: remoteNs,
+			Name:      "coolpod",
+			UID:       "remote-uid",
+			Labels: map[string]string{
+				remote.LabelKeyNodeName:  nodeName,
+				remote.LabelKeyNamespace: ns,
+			},
+		},
+	}
+
+	type args struct {
+		s    *stats.Summary
+		pods []corev1.Pod
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   []stats.PodStats
+	}{
+		"NoPods": {
+			reason: "Stats for pods that were not supplied should be omitted",
+			args: args{
+				s: &stats.Summary{Pods: []stats.PodStats{
+					{PodRef: stats.PodReference{Namespace: "other", Name: "coolpod"}},
+				}},
+			},
+			want: []stats.PodStats{},
+		},
+		"Pods": {
+			reason: "Stats for supplied pods should be returned, referring to their local pods",
+			args: args{
+				s: &stats.Summary{Pods: []stats.PodStats{
+					{
+						PodRef: stats.PodReference{Namespace: remoteNs, Name: "coolpod", UID: "remote-uid"},
+						CPU:    &stats.CPUStats{UsageNanoCores: &cores},
+					},
+					{PodRef: stats.PodReference{Namespace: "other", Name: "coolpod"}},
+				}},
+				pods: []corev1.Pod{pod},
+			},
+			want: []stats.PodStats{
+				{
+					PodRef: stats.PodReference{Namespace: ns, Name: "coolpod"},
+					CPU:    &stats.CPUStats{UsageNanoCores: &cores},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := FilterPodStats(tc.args.s, tc.args.pods)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nFilterPodStats(...): -want, +got: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package kubernetes
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kcache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+// A DependencySyncer keeps the remote copies of local config maps and secrets
+// in sync with the local originals, much like a real kubelet updates mounted
+// config map and secret volumes when their source changes.
+type DependencySyncer struct {
+	local    client.Reader
+	remotes  []resource.Applicator
+	nodeName string
+	namer    remote.NamespaceNamer
+}
+
+// A DependencySyncerOption configures a DependencySyncer.
+type DependencySyncerOption func(*DependencySyncer)
+
+// WithNamespaceNamer configures how a DependencySyncer names the remote
+// namespaces it syncs dependencies to.
+func WithNamespaceNamer(n remote.NamespaceNamer) DependencySyncerOption {
+	return func(s *DependencySyncer) {
+		s.namer = n
+	}
+}
+
+// NewDependencySyncer returns a DependencySyncer that applies changes to local
+// config maps and secrets to the supplied remote API servers, as long as
+// they're depended on by a local pod scheduled to the supplied node.
+func NewDependencySyncer(local client.Reader, remotes []resource.Applicator, nodeName string, o ...DependencySyncerOption) *DependencySyncer {
+	s := &DependencySyncer{local: local, remotes: remotes, nodeName: nodeName, namer: remote.HashNamespaceNamer}
+	for _, fn := range o {
+		fn(s)
+	}
+	return s
+}
+
+// Start syncing config maps and secrets when the supplied informers observe
+// that they have been created or updated.
+func (s *DependencySyncer) Start(ctx context.Context, i cache.Informers) error {
+	for _, o := range []runtime.Object{&corev1.ConfigMap{}, &corev1.Secret{}} {
+		inf, err := i.GetInformer(ctx, o)
+		if err != nil {
+			return errors.Wrap(err, "cannot get informer")
+		}
+		inf.AddEventHandler(kcache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				s.sync(ctx, obj)
+			},
+			UpdateFunc: func(_, obj interface{}) {
+				s.sync(ctx, obj)
+			},
+		})
+	}
+	return nil
+}
+
+func (s *DependencySyncer) sync(ctx context.Context, obj interface{}) {
+	o, ok := obj.(runtime.Object)
+	if !ok {
+		return
+	}
+	if err := s.Sync(ctx, o); err != nil {
+		log.G(ctx).WithError(err).Error("cannot sync pod dependency")
+	}
+}
+
+// Sync the supplied local config map or secret to the remote API servers if it
+// is depended on by any local pod scheduled to our node.
+func (s *DependencySyncer) Sync(ctx context.Context, obj runtime.Object) error {
+	var want DependencyKind
+	switch obj.(type) {
+	case *corev1.ConfigMap:
+		want = DependencyKindConfigMap
+	case *corev1.Secret:
+		want = DependencyKindSecret
+	default:
+		return nil
+	}
+
+	om, ok := obj.(metav1.Object)
+	if !ok {
+		return nil
+	}
+
+	pl := &corev1.PodList{}
+	if err := s.local.List(ctx, pl, client.InNamespace(om.GetNamespace())); err != nil {
+		return errors.Wrap(err, "cannot list local pods")
+	}
+
+	found := false
+	token := false
+	for i := range pl.Items {
+		pod := &pl.Items[i]
+		if pod.Spec.NodeName != s.nodeName {
+			continue
+		}
+		for _, d := range FindPodDependencies(pod) {
+			if keyFor(d) != (dependencyKey{kind: want, name: om.GetName()}) {
+				continue
+			}
+			found = true
+			token = token || d.Kind == DependencyKindServiceAccountTokenSecret
+		}
+	}
+
+	if !found {
+		return nil
+	}
+
+	rmt := obj.DeepCopyObject()
+	if token {
+		remote.PrepareServiceAccountTokenSecret(rmt.(*corev1.Secret))
+	}
+	remote.PrepareObject(s.nodeName, rmt, remote.WithNamespaceNamer(s.namer))
+
+	for _, a := range s.remotes {
+		// The remote namespace will not exist in remote clusters that are not
+		// running any pods from the local namespace. There's no need to sync
+		// our dependency to those clusters.
+		err := a.Apply(ctx, rmt.DeepCopyObject())
+		if kerrors.IsNotFound(errors.Cause(err)) {
+			continue
+		}
+		if err != nil {
+			return errors.Wrap(err, "cannot apply remote pod dependency")
+		}
+	}
+	return nil
+}
+
+// A ClaimStatusSyncer reports the status of remote persistent volume claims to
+// their corresponding local claims.
+type ClaimStatusSyncer struct {
+	local    client.Client
+	nodeName string
+}
+
+// NewClaimStatusSyncer returns a ClaimStatusSyncer that reports the status of
+// remote persistent volume claims created on behalf of the supplied node to
+// the local API server.
+func NewClaimStatusSyncer(local client.Client, nodeName string) *ClaimStatusSyncer {
+	return &ClaimStatusSyncer{local: local, nodeName: nodeName}
+}
+
+// Start syncing claim status when the supplied (remote) informers observe that
+// a persistent volume claim has been created or updated.
+func (s *ClaimStatusSyncer) Start(ctx context.Context, i cache.Informers) error {
+	inf, err := i.GetInformer(ctx, &corev1.PersistentVolumeClaim{})
+	if err != nil {
+		return errors.Wrap(err, "cannot get informer")
+	}
+	inf.AddEventHandler(kcache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			s.sync(ctx, obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			s.sync(ctx, obj)
+		},
+	})
+	return nil
+}
+
+func (s *ClaimStatusSyncer) sync(ctx context.Context, obj interface{}) {
+	c, ok := obj.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		return
+	}
+	if err := s.Sync(ctx, c); err != nil {
+		log.G(ctx).WithError(err).Error("cannot sync persistent volume claim status")
+	}
+}
+
+// Sync the status of the supplied remote persistent volume claim to its local
+// claim, if it was created on behalf of our node.
+func (s *ClaimStatusSyncer) Sync(ctx context.Context, rmt *corev1.PersistentVolumeClaim) error {
+	if rmt.GetLabels()[remote.LabelKeyNodeName] != s.nodeName {
+		return nil
+	}
+
+	lcl := &corev1.PersistentVolumeClaim{}
+	nn := types.NamespacedName{Namespace: rmt.GetLabels()[remote.LabelKeyNamespace], Name: rmt.GetName()}
+	if err := s.local.Get(ctx, nn, lcl); err != nil {
+		return errors.Wrap(resource.IgnoreNotFound(err), "cannot get local persistent volume claim")
+	}
+
+	if equality.Semantic.DeepEqual(lcl.Status, rmt.Status) {
+		return nil
+	}
+
+	// NOTE(negz): The local persistent volume controller may revert this
+	// status if it believes the local claim is unbound. The remote claim's
+	// status is still informative to anyone inspecting the local claim.
+	lcl.Status = *rmt.Status.DeepCopy()
+	return errors.Wrap(s.local.Status().Update(ctx, lcl), "cannot update local persistent volume claim status")
+}
@@ -0,0 +1,244 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+func TestDependencySyncerSync(t *testing.T) {
+	errBoom := errors.New("boom")
+	nodeName := "coolnode"
+	ns := "coolns"
+	name := "coolcm"
+
+	pod := func(nodeName string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "coolpod"},
+			Spec: corev1.PodSpec{
+				NodeName: nodeName,
+				Containers: []corev1.Container{{
+					EnvFrom: []corev1.EnvFromSource{{
+						ConfigMapRef: &corev1.ConfigMapEnvSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: name},
+						},
+					}},
+				}},
+			},
+		}
+	}
+
+	type want struct {
+		applied runtime.Object
+		err     error
+	}
+	cases := map[string]struct {
+		reason string
+		c      client.Reader
+		a      resource.Applicator
+		obj    runtime.Object
+		want   want
+	}{
+		"ListPodsError": {
+			reason: "Errors listing local pods should be returned",
+			c: &test.MockClient{
+				MockList: test.NewMockListFn(errBoom),
+			},
+			obj: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}},
+			want: want{
+				err: errors.Wrap(errBoom, "cannot list local pods"),
+			},
+		},
+		"NotDependedOn": {
+			reason: "Config maps that are not depended on by a pod scheduled to our node should not be synced",
+			c: &test.MockClient{
+				MockList: test.NewMockListFn(nil, func(obj runtime.Object) error {
+					obj.(*corev1.PodList).Items = []corev1.Pod{pod("othernode")}
+					return nil
+				}),
+			},
+			obj: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}},
+		},
+		"RemoteNamespaceNotFound": {
+			reason: "Config maps should not be synced to remote clusters that lack the remote namespace",
+			c: &test.MockClient{
+				MockList: test.NewMockListFn(nil, func(obj runtime.Object) error {
+					obj.(*corev1.PodList).Items = []corev1.Pod{pod(nodeName)}
+					return nil
+				}),
+			},
+			a: resource.ApplyFn(func(_ context.Context, _ runtime.Object, _ ...resource.ApplyOption) error {
+				return errors.Wrap(kerrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, ns), "cannot create object")
+			}),
+			obj: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}},
+		},
+		"ApplyError": {
+			reason: "Errors applying the remote config map should be returned",
+			c: &test.MockClient{
+				MockList: test.NewMockListFn(nil, func(obj runtime.Object) error {
+					obj.(*corev1.PodList).Items = []corev1.Pod{pod(nodeName)}
+					return nil
+				}),
+			},
+			a: resource.ApplyFn(func(_ context.Context, _ runtime.Object, _ ...resource.ApplyOption) error {
+				return errBoom
+			}),
+			obj: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}},
+			want: want{
+				err: errors.Wrap(errBoom, "cannot apply remote pod dependency"),
+			},
+		},
+		"DependedOn": {
+			reason: "Config maps that are depended on by a pod scheduled to our node should be synced",
+			c: &test.MockClient{
+				MockList: test.NewMockListFn(nil, func(obj runtime.Object) error {
+					obj.(*corev1.PodList).Items = []corev1.Pod{pod(nodeName)}
+					return nil
+				}),
+			},
+			obj: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+				Data:       map[string]string{"cool": "very"},
+			},
+			want: want{
+				applied: &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: remote.NamespaceName(nodeName, ns),
+						Name:      name,
+						Labels: map[string]string{
+							remote.LabelKeyNodeName:  nodeName,
+							remote.LabelKeyNamespace: ns,
+						},
+					},
+					Data: map[string]string{"cool": "very"},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var applied runtime.Object
+			var a resource.Applicator = resource.ApplyFn(func(_ context.Context, obj runtime.Object, _ ...resource.ApplyOption) error {
+				applied = obj
+				return nil
+			})
+			if tc.a != nil {
+				a = tc.a
+			}
+
+			s := NewDependencySyncer(tc.c, []resource.Applicator{a}, nodeName)
+			err := s.Sync(context.Background(), tc.obj)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ns.Sync(...): -want error, +got error: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.applied, applied); diff != "" {
+				t.Errorf("\n%s\ns.Sync(...): -want applied, +got applied: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestClaimStatusSyncerSync(t *testing.T) {
+	errBoom := errors.New("boom")
+	nodeName := "coolnode"
+	ns := "coolns"
+	name := "coolclaim"
+
+	rmt := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: remote.NamespaceName(nodeName, ns),
+			Name:      name,
+			Labels: map[string]string{
+				remote.LabelKeyNodeName:  nodeName,
+				remote.LabelKeyNamespace: ns,
+			},
+		},
+		Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+	}
+
+	type want struct {
+		updated runtime.Object
+		err     error
+	}
+	cases := map[string]struct {
+		reason string
+		c      *test.MockClient
+		rmt    *corev1.PersistentVolumeClaim
+		want   want
+	}{
+		"OtherNode": {
+			reason: "Claims created on behalf of another node should be ignored",
+			c:      &test.MockClient{},
+			rmt:    &corev1.PersistentVolumeClaim{},
+		},
+		"LocalClaimNotFound": {
+			reason: "Claims whose local claim does not exist should be ignored",
+			c: &test.MockClient{
+				MockGet: test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{}, name)),
+			},
+			rmt: rmt,
+		},
+		"GetError": {
+			reason: "Errors getting the local claim should be returned",
+			c: &test.MockClient{
+				MockGet: test.NewMockGetFn(errBoom),
+			},
+			rmt: rmt,
+			want: want{
+				err: errors.Wrap(errBoom, "cannot get local persistent volume claim"),
+			},
+		},
+		"StatusChanged": {
+			reason: "The remote claim's status should be reported to the local claim",
+			c: &test.MockClient{
+				MockGet: test.NewMockGetFn(nil, func(obj runtime.Object) error {
+					c := obj.(*corev1.PersistentVolumeClaim)
+					c.SetNamespace(ns)
+					c.SetName(name)
+					c.Status.Phase = corev1.ClaimPending
+					return nil
+				}),
+			},
+			rmt: rmt,
+			want: want{
+				updated: &corev1.PersistentVolumeClaim{
+					ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+					Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var updated runtime.Object
+			tc.c.MockStatusUpdate = func(_ context.Context, obj runtime.Object, _ ...client.UpdateOption) error {
+				updated = obj
+				return nil
+			}
+
+			s := NewClaimStatusSyncer(tc.c, nodeName)
+			err := s.Sync(context.Background(), tc.rmt)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ns.Sync(...): -want error, +got error: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.updated, updated); diff != "" {
+				t.Errorf("\n%s\ns.Sync(...): -want updated, +got updated: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package kubernetes
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/virtual-kubelet/virtual-kubelet/log"
+	authv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+
+	"github.com/negz/actual-kubelets/internal/pointer"
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+const (
+	// The lifetime of a projected service account token that does not
+	// specify one. Matches the API server's default.
+	defaultTokenExpirationSeconds = 3600
+
+	defaultRotationInterval = 1 * time.Minute
+)
+
+// A TokenIssuer issues service account tokens.
+type TokenIssuer interface {
+	// IssueToken issues a token for the supplied service account.
+	IssueToken(ctx context.Context, namespace, serviceAccount string, tr *authv1.TokenRequest) (*authv1.TokenRequest, error)
+}
+
+// A TokenIssuerFn issues service account tokens.
+type TokenIssuerFn func(ctx context.Context, namespace, serviceAccount string, tr *authv1.TokenRequest) (*authv1.TokenRequest, error)
+
+// IssueToken issues a token for the supplied service account.
+func (fn TokenIssuerFn) IssueToken(ctx context.Context, namespace, serviceAccount string, tr *authv1.TokenRequest) (*authv1.TokenRequest, error) {
+	return fn(ctx, namespace, serviceAccount, tr)
+}
+
+// APITokenIssuer returns a TokenIssuer that issues service account tokens using
+// the TokenRequest API of the supplied clientset.
+func APITokenIssuer(c kubernetes.Interface) TokenIssuerFn {
+	return func(ctx context.Context, namespace, serviceAccount string, tr *authv1.TokenRequest) (*authv1.TokenRequest, error) {
+		return c.CoreV1().ServiceAccounts(namespace).CreateToken(ctx, serviceAccount, tr, metav1.CreateOptions{})
+	}
+}
+
+// IssueServiceAccountTokens returns a secret containing a token for each
+// service account token projection of the supplied pod's projected volume that
+// corresponds to the supplied secret name. Tokens are bound to the pod, and
+// thus become invalid when it is deleted. The secret is annotated with the time
+// after which its tokens should be rotated; when 80% of the shortest lived
+// token's requested lifetime has elapsed.
+func IssueServiceAccountTokens(ctx context.Context, ti TokenIssuer, pod *corev1.Pod, name string) (*corev1.Secret, error) {
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: pod.GetNamespace(), Name: name},
+		Data:       map[string][]byte{},
+	}
+
+	var rotateAfter *time.Time
+	for _, v := range pod.Spec.Volumes {
+		if v.Projected == nil || remote.ServiceAccountTokenSecretName(pod.GetName(), v.Name) != name {
+			continue
+		}
+		for _, src := range v.Projected.Sources {
+			p := src.ServiceAccountToken
+			if p == nil {
+				continue
+			}
+			tr := &authv1.TokenRequest{Spec: authv1.TokenRequestSpec{
+				ExpirationSeconds: pointer.Int64(pointer.DerefInt64Or(p.ExpirationSeconds, defaultTokenExpirationSeconds)),
+				BoundObjectRef: &authv1.BoundObjectReference{
+					Kind:       "Pod",
+					APIVersion: "v1",
+					Name:       pod.GetName(),
+					UID:        pod.GetUID(),
+				},
+			}}
+			if p.Audience != "" {
+				tr.Spec.Audiences = []string{p.Audience}
+			}
+			issued, err := ti.IssueToken(ctx, pod.GetNamespace(), serviceAccountName(pod), tr)
+			if err != nil {
+				return nil, errors.Wrap(err, "cannot issue service account token")
+			}
+			s.Data[remote.ServiceAccountTokenKey(p.Path)] = []byte(issued.Status.Token)
+
+			// Rotate once 80% of the token's lifetime has elapsed, like the
+			// kubelet does.
+			lifetime := time.Duration(*tr.Spec.ExpirationSeconds) * time.Second
+			ra := issued.Status.ExpirationTimestamp.Add(-lifetime / 5)
+			if rotateAfter == nil || ra.Before(*rotateAfter) {
+				rotateAfter = &ra
+			}
+		}
+	}
+
+	if rotateAfter != nil {
+		meta.AddAnnotations(s, map[string]string{remote.AnnotationKeyTokenRotateAfter: rotateAfter.UTC().Format(time.RFC3339)})
+	}
+
+	return s, nil
+}
+
+func serviceAccountName(pod *corev1.Pod) string {
+	if n := pod.Spec.ServiceAccountName; n != "" {
+		return n
+	}
+	if n := pod.Spec.DeprecatedServiceAccount; n != "" {
+		return n
+	}
+	return "default"
+}
+
+// A TokenRotator rotates the projected service account tokens that were issued
+// by the local API server and replicated to remote clusters, before they
+// expire.
+type TokenRotator struct {
+	local    client.Reader
+	issuer   TokenIssuer
+	remotes  []RemoteCluster
+	nodeName string
+	namer    remote.NamespaceNamer
+	interval time.Duration
+}
+
+// NewTokenRotator returns a TokenRotator that rotates the projected service
+// account tokens of all local pods scheduled to the supplied node.
+func NewTokenRotator(local client.Reader, ti TokenIssuer, remotes []RemoteCluster, nodeName string, namer remote.NamespaceNamer) *TokenRotator {
+	return &TokenRotator{
+		local:    local,
+		issuer:   ti,
+		remotes:  remotes,
+		nodeName: nodeName,
+		namer:    namer,
+		interval: defaultRotationInterval,
+	}
+}
+
+// Run the TokenRotator, rotating tokens at its configured interval until the
+// supplied context is done.
+func (r *TokenRotator) Run(ctx context.Context) {
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := r.Rotate(ctx); err != nil {
+				log.G(ctx).WithError(err).Error("cannot rotate service account tokens")
+			}
+		}
+	}
+}
+
+// Rotate any replicated service account tokens that are due for rotation.
+func (r *TokenRotator) Rotate(ctx context.Context) error {
+	pl := &corev1.PodList{}
+	if err := r.local.List(ctx, pl); err != nil {
+		return errors.Wrap(err, "cannot list local pods")
+	}
+
+	for i := range pl.Items {
+		pod := &pl.Items[i]
+		if pod.Spec.NodeName != r.nodeName {
+			continue
+		}
+		for _, v := range pod.Spec.Volumes {
+			if !remote.HasServiceAccountTokenProjection(v) {
+				continue
+			}
+			if err := r.rotate(ctx, pod, remote.ServiceAccountTokenSecretName(pod.GetName(), v.Name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *TokenRotator) rotate(ctx context.Context, pod *corev1.Pod, name string) error {
+	nn := types.NamespacedName{Namespace: r.namer.NamespaceName(r.nodeName, pod.GetNamespace()), Name: name}
+	for _, rc := range r.remotes {
+		s := &corev1.Secret{}
+		err := rc.Get(ctx, nn, s)
+		if kerrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "cannot get service account token secret from remote cluster %q", rc.Name)
+		}
+		if !rotationDue(s) {
+			continue
+		}
+
+		fresh, err := IssueServiceAccountTokens(ctx, r.issuer, pod, name)
+		if err != nil {
+			return err
+		}
+		remote.PrepareObject(r.nodeName, fresh, remote.WithNamespaceNamer(r.namer))
+		if err := rc.Apply(ctx, fresh); err != nil {
+			return errors.Wrapf(err, "cannot apply service account token secret to remote cluster %q", rc.Name)
+		}
+	}
+	return nil
+}
+
+// rotationDue returns true if the supplied token secret should be rotated.
+// Secrets that don't indicate when they should be rotated are always due.
+func rotationDue(s *corev1.Secret) bool {
+	t, err := time.Parse(time.RFC3339, s.GetAnnotations()[remote.AnnotationKeyTokenRotateAfter])
+	if err != nil {
+		return true
+	}
+	return time.Now().After(t)
+}
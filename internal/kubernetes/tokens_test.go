@@ -0,0 +1,127 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pkg/errors"
+	authv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/negz/actual-kubelets/internal/remote"
+)
+
+func TestTokenRotatorRotate(t *testing.T) {
+	errBoom := errors.New("boom")
+	nodeName := "coolnode"
+	ns := "coolns"
+	secretName := "coolpod-coolvolume-token"
+
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "coolpod"},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Volumes: []corev1.Volume{{
+				Name: "coolvolume",
+				VolumeSource: corev1.VolumeSource{
+					Projected: &corev1.ProjectedVolumeSource{
+						Sources: []corev1.VolumeProjection{
+							{ServiceAccountToken: &corev1.ServiceAccountTokenProjection{Path: "token"}},
+						},
+					},
+				},
+			}},
+		},
+	}
+	secret := func(rotateAfter time.Time) func(obj runtime.Object) error {
+		return func(obj runtime.Object) error {
+			obj.(*corev1.Secret).SetAnnotations(map[string]string{
+				remote.AnnotationKeyTokenRotateAfter: rotateAfter.Format(time.RFC3339),
+			})
+			return nil
+		}
+	}
+	ti := TokenIssuerFn(func(_ context.Context, _, _ string, tr *authv1.TokenRequest) (*authv1.TokenRequest, error) {
+		tr.Status.Token = "cooltoken"
+		return tr, nil
+	})
+
+	type want struct {
+		applied []string
+		err     error
+	}
+	cases := map[string]struct {
+		reason string
+		get    test.MockGetFn
+		ti     TokenIssuer
+		want   want
+	}{
+		"NotReplicated": {
+			reason: "Tokens that have not been replicated to a remote cluster should not be rotated",
+			get:    test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{}, secretName)),
+			ti:     ti,
+		},
+		"NotDue": {
+			reason: "Tokens that are not yet due for rotation should not be rotated",
+			get:    test.NewMockGetFn(nil, secret(time.Now().Add(1*time.Hour))),
+			ti:     ti,
+		},
+		"Due": {
+			reason: "Tokens that are due for rotation should be reissued and applied",
+			get:    test.NewMockGetFn(nil, secret(time.Now().Add(-1*time.Minute))),
+			ti:     ti,
+			want: want{
+				applied: []string{secretName},
+			},
+		},
+		"IssueError": {
+			reason: "Errors issuing tokens should be returned",
+			get:    test.NewMockGetFn(nil, secret(time.Now().Add(-1*time.Minute))),
+			ti: TokenIssuerFn(func(_ context.Context, _, _ string, _ *authv1.TokenRequest) (*authv1.TokenRequest, error) {
+				return nil, errBoom
+			}),
+			want: want{
+				err: errors.Wrap(errBoom, "cannot issue service account token"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			applied := make([]string, 0)
+
+			lcl := &test.MockClient{
+				MockList: test.NewMockListFn(nil, func(obj runtime.Object) error {
+					obj.(*corev1.PodList).Items = []corev1.Pod{pod}
+					return nil
+				}),
+			}
+			rc := RemoteCluster{Name: "coolremote", Client: Client{ClientApplicator: resource.ClientApplicator{
+				Client: &test.MockClient{MockGet: tc.get},
+				Applicator: resource.ApplyFn(func(_ context.Context, obj runtime.Object, _ ...resource.ApplyOption) error {
+					applied = append(applied, obj.(metav1.Object).GetName())
+					return nil
+				}),
+			}}}
+
+			r := NewTokenRotator(lcl, tc.ti, []RemoteCluster{rc}, nodeName, remote.HashNamespaceNamer)
+			err := r.Rotate(context.Background())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nr.Rotate(...): -want error, +got error: \n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.applied, applied, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nr.Rotate(...): -want applied, +got applied: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package kubernetes
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/label"
+	"go.opentelemetry.io/otel/propagators"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"google.golang.org/grpc/credentials"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/transport"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	tracerName  = "github.com/negz/actual-kubelets"
+	serviceName = "actual-kubelets"
+)
+
+// Span attributes.
+const (
+	attrRemote    = label.Key("actual.vk.remote")
+	attrNamespace = label.Key("k8s.namespace.name")
+	attrPod       = label.Key("k8s.pod.name")
+	attrKind      = label.Key("k8s.object.kind")
+	attrName      = label.Key("k8s.object.name")
+)
+
+// A TracerProvider exports spans over OTLP to the configured endpoint. The
+// TracerProvider should be shut down when it's no longer needed in order to
+// flush any buffered spans.
+type TracerProvider struct {
+	*sdktrace.TracerProvider
+
+	processor sdktrace.SpanProcessor
+	exporter  *otlp.Exporter
+}
+
+// NewTracerProvider returns a TracerProvider that exports spans over OTLP to
+// the configured endpoint.
+func NewTracerProvider(cfg TracingConfig) (*TracerProvider, error) {
+	// Connect using TLS with the system's root certificates unless configured
+	// to be insecure.
+	tls := otlp.WithTLSCredentials(credentials.NewClientTLSFromCert(nil, ""))
+	if cfg.Insecure {
+		tls = otlp.WithInsecure()
+	}
+	exp, err := otlp.NewExporter(otlp.WithAddress(cfg.Endpoint), tls)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create OTLP trace exporter")
+	}
+
+	bsp := sdktrace.NewBatchSpanProcessor(exp)
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(bsp),
+		sdktrace.WithResource(sdkresource.New(semconv.ServiceNameKey.String(serviceName))),
+	)
+	return &TracerProvider{TracerProvider: tp, processor: bsp, exporter: exp}, nil
+}
+
+// Shutdown the TracerProvider, flushing any buffered spans.
+func (tp *TracerProvider) Shutdown(ctx context.Context) error {
+	// Unregistering the batch span processor shuts it down, which flushes any
+	// spans it has queued to the exporter.
+	tp.TracerProvider.UnregisterSpanProcessor(tp.processor)
+	return errors.Wrap(tp.exporter.Shutdown(ctx), "cannot shut down OTLP trace exporter")
+}
+
+// startSpan starts a span with the supplied name and attributes using the
+// global TracerProvider, which is a no-op unless tracing is configured.
+func startSpan(ctx context.Context, name string, attrs ...label.KeyValue) (context.Context, trace.Span) {
+	return global.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// podAttributes returns span attributes identifying the supplied local pod.
+func podAttributes(namespace, name string, attrs ...label.KeyValue) []label.KeyValue {
+	return append([]label.KeyValue{attrNamespace.String(namespace), attrPod.String(name)}, attrs...)
+}
+
+// endSpan ends the supplied span, recording the supplied error if any. It's
+// intended to be deferred with a pointer to a named error return value.
+func endSpan(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
+
+// TraceContext returns the W3C trace context (i.e. traceparent) of the span in
+// the supplied context, or an empty string if there is no such span.
+func TraceContext(ctx context.Context) string {
+	c := mapCarrier{}
+	propagators.TraceContext{}.Inject(ctx, c)
+	return c.Get("traceparent")
+}
+
+// A mapCarrier is an otel.TextMapCarrier backed by a map.
+type mapCarrier map[string]string
+
+func (c mapCarrier) Get(key string) string        { return c[key] }
+func (c mapCarrier) Set(key string, value string) { c[key] = value }
+
+// A tracingClient is a client.Client that records a span for each call to the
+// API server.
+type tracingClient struct {
+	client.Client
+	name string
+}
+
+// NewTracingClient returns a client.Client that records a span for each call
+// made by the supplied client. Spans are attributed to the supplied cluster
+// name.
+func NewTracingClient(c client.Client, cluster string) client.Client {
+	return &tracingClient{Client: c, name: cluster}
+}
+
+func (c *tracingClient) start(ctx context.Context, op string, obj runtime.Object) (context.Context, trace.Span) {
+	attrs := []label.KeyValue{attrRemote.String(c.name), attrKind.String(kindOf(obj))}
+	if m, ok := obj.(metav1.Object); ok {
+		attrs = append(attrs, attrNamespace.String(m.GetNamespace()), attrName.String(m.GetName()))
+	}
+	return startSpan(ctx, "Client."+op, attrs...)
+}
+
+func kindOf(obj runtime.Object) string {
+	if k := obj.GetObjectKind().GroupVersionKind().Kind; k != "" {
+		return k
+	}
+	t := reflect.TypeOf(obj)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// Get the supplied object, recording a span.
+func (c *tracingClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) (err error) {
+	ctx, span := c.start(ctx, "Get", obj)
+	span.SetAttributes(attrNamespace.String(key.Namespace), attrName.String(key.Name))
+	defer endSpan(span, &err)
+	return c.Client.Get(ctx, key, obj)
+}
+
+// List the supplied objects, recording a span.
+func (c *tracingClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) (err error) {
+	ctx, span := c.start(ctx, "List", list)
+	defer endSpan(span, &err)
+	return c.Client.List(ctx, list, opts...)
+}
+
+// Create the supplied object, recording a span.
+func (c *tracingClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) (err error) {
+	ctx, span := c.start(ctx, "Create", obj)
+	defer endSpan(span, &err)
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+// Update the supplied object, recording a span.
+func (c *tracingClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) (err error) {
+	ctx, span := c.start(ctx, "Update", obj)
+	defer endSpan(span, &err)
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+// Patch the supplied object, recording a span.
+func (c *tracingClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) (err error) {
+	ctx, span := c.start(ctx, "Patch", obj)
+	defer endSpan(span, &err)
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+// Delete the supplied object, recording a span.
+func (c *tracingClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) (err error) {
+	ctx, span := c.start(ctx, "Delete", obj)
+	defer endSpan(span, &err)
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+// DeleteAllOf the supplied object, recording a span.
+func (c *tracingClient) DeleteAllOf(ctx context.Context, obj runtime.Object, opts ...client.DeleteAllOfOption) (err error) {
+	ctx, span := c.start(ctx, "DeleteAllOf", obj)
+	defer endSpan(span, &err)
+	return c.Client.DeleteAllOf(ctx, obj, opts...)
+}
+
+// A tracingTransport is an http.RoundTripper that records a span for each
+// request to the API server. It's used to trace the calls a Kubernetes
+// clientset makes, e.g. to stream logs or proxy stats, which don't go through
+// a tracingClient.
+type tracingTransport struct {
+	http.RoundTripper
+	name string
+}
+
+// NewTracingTransport returns a transport.WrapperFunc that records a span for
+// each request made by the wrapped http.RoundTripper. Spans are attributed to
+// the supplied cluster name.
+func NewTracingTransport(cluster string) transport.WrapperFunc {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &tracingTransport{RoundTripper: rt, name: cluster}
+	}
+}
+
+// RoundTrip the supplied request, recording a span. The span ends when the
+// response headers are received; it does not cover reading the body of a
+// streaming response (e.g. logs or a watch).
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	attrs := append([]label.KeyValue{attrRemote.String(t.name)}, semconv.HTTPClientAttributesFromHTTPRequest(req)...)
+	ctx, span := startSpan(req.Context(), "HTTP "+req.Method, attrs...)
+	defer span.End()
+
+	rsp, err := t.RoundTripper.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(rsp.StatusCode)...)
+	span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(rsp.StatusCode))
+	return rsp, nil
+}
@@ -0,0 +1,124 @@
+package kubernetes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/codes"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// A spanRecorder is an sdktrace.SpanProcessor that records ended spans.
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []*export.SpanData
+}
+
+func (r *spanRecorder) OnStart(_ *export.SpanData) {}
+func (r *spanRecorder) Shutdown()                  {}
+func (r *spanRecorder) ForceFlush()                {}
+
+func (r *spanRecorder) OnEnd(sd *export.SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, sd)
+}
+
+func TestTraceContext(t *testing.T) {
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer(tracerName).Start(context.Background(), "cool")
+	defer span.End()
+
+	sc := span.SpanContext()
+
+	cases := map[string]struct {
+		reason string
+		ctx    context.Context
+		want   string
+	}{
+		"NoSpan": {
+			reason: "An empty string should be returned when the context has no span",
+			ctx:    context.Background(),
+			want:   "",
+		},
+		"SampledSpan": {
+			reason: "The traceparent of the span in the context should be returned",
+			ctx:    ctx,
+			want:   "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-01",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := TraceContext(tc.ctx)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nTraceContext(...): -want, +got: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestTracingTransport(t *testing.T) {
+	type want struct {
+		name   string
+		status codes.Code
+		remote string
+	}
+	cases := map[string]struct {
+		reason string
+		status int
+		want   want
+	}{
+		"Success": {
+			reason: "A successful request should be recorded as a span",
+			status: http.StatusOK,
+			want:   want{name: "HTTP GET", status: codes.Unset, remote: "cool"},
+		},
+		"Forbidden": {
+			reason: "A failed request should be recorded as an errored span",
+			status: http.StatusForbidden,
+			want:   want{name: "HTTP GET", status: codes.Error, remote: "cool"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := &spanRecorder{}
+			prev := global.TracerProvider()
+			global.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(r)))
+			defer global.SetTracerProvider(prev)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tc.status)
+			}))
+			defer srv.Close()
+
+			c := &http.Client{Transport: NewTracingTransport("cool")(http.DefaultTransport)}
+			rsp, err := c.Get(srv.URL)
+			if err != nil {
+				t.Fatalf("c.Get(...): %s", err)
+			}
+			_ = rsp.Body.Close()
+
+			if len(r.spans) != 1 {
+				t.Fatalf("\n%s\nc.Get(...): want 1 span, got %d", tc.reason, len(r.spans))
+			}
+			sd := r.spans[0]
+			got := want{name: sd.Name, status: sd.StatusCode}
+			for _, kv := range sd.Attributes {
+				if kv.Key == attrRemote {
+					got.remote = kv.Value.AsString()
+				}
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nc.Get(...): -want, +got: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -23,3 +23,31 @@ func Int64OrNil(i int) *int64 {
 func Bool(b bool) *bool {
 	return &b
 }
+
+// DerefInt64Or dereferences and returns the supplied pointer. If the pointer
+// is nil, it returns the supplied default value.
+func DerefInt64Or(i *int64, dflt int64) int64 {
+	if i == nil {
+		return dflt
+	}
+	return *i
+}
+
+// Int64 returns a pointer to the supplied int64.
+func Int64(i int64) *int64 {
+	return &i
+}
+
+// DerefStringOr dereferences and returns the supplied pointer. If the pointer
+// is nil, it returns the supplied default value.
+func DerefStringOr(s *string, dflt string) string {
+	if s == nil {
+		return dflt
+	}
+	return *s
+}
+
+// String returns a pointer to the supplied string.
+func String(s string) *string {
+	return &s
+}
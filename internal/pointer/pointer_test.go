@@ -93,3 +93,61 @@ func TestBool(t *testing.T) {
 		})
 	}
 }
+
+func TestDerefInt64Or(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		i      *int64
+		dflt   int64
+		want   int64
+	}{
+		"Nil": {
+			reason: "A nil pointer should return the default value",
+			dflt:   42,
+			want:   42,
+		},
+		"NotNil": {
+			reason: "A non-nil pointer should be dereferenced",
+			i:      Int64(7),
+			dflt:   42,
+			want:   7,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := DerefInt64Or(tc.i, tc.dflt)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nDerefInt64Or(...): -want, +got: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDerefStringOr(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		s      *string
+		dflt   string
+		want   string
+	}{
+		"Nil": {
+			reason: "A nil pointer should return the default value",
+			dflt:   "default",
+			want:   "default",
+		},
+		"NotNil": {
+			reason: "A non-nil pointer should be dereferenced",
+			s:      String("cool"),
+			dflt:   "default",
+			want:   "cool",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := DerefStringOr(tc.s, tc.dflt)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nDerefStringOr(...): -want, +got: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -3,12 +3,19 @@ package remote
 import (
 	"fmt"
 	"hash/fnv"
+	"reflect"
 	"strings"
+	"text/template"
 
+	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	nodev1beta1 "k8s.io/api/node/v1beta1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"github.com/negz/actual-kubelets/internal/pointer"
 
@@ -33,27 +40,71 @@ const (
 	// token secrets to indicate the service account they are associated with.
 	AnnotationKeyServiceAccountName = "actual.vk/replicated-service-account.name"
 
+	// AnnotationKeyTokenRotateAfter is added to secrets containing service
+	// account tokens issued by the local API server to indicate the time
+	// (in RFC 3339 format) after which they should be rotated.
+	AnnotationKeyTokenRotateAfter = "actual.vk/token-rotate-after"
+
+	// LabelKeyExposingService is added to remote pods that are exposed by a
+	// remote service in order to make them reachable from the local cluster.
+	// Its value is the name of the service.
+	LabelKeyExposingService = "actual.vk/exposing-service"
+
+	// NamespaceQuotaName is the name of the resource quota and limit range
+	// AK creates in each remote namespace.
+	NamespaceQuotaName = "actual-kubelets"
+
+	// AnnotationKeyTraceContext is added to remote pods to record the W3C
+	// trace context (i.e. traceparent) of the trace in which they were
+	// created, so that remote tooling may correlate them with AK's traces.
+	AnnotationKeyTraceContext = "actual.vk/trace-context"
+
 	// SecretTypeReplicatedServiceAccountToken indicates that a secret is a
 	// service account token replicated by the Virtual Kubelet so that a remote
 	// pod may connect to the local API.
 	SecretTypeReplicatedServiceAccountToken corev1.SecretType = "actual.vk/replicated-service-account-token"
 )
 
+type objectOptions struct {
+	namer NamespaceNamer
+}
+
+// An ObjectOption influences how an object is prepared for the remote cluster.
+type ObjectOption func(*objectOptions)
+
+// WithNamespaceNamer determines how the remote namespace of an object is
+// named. Remote namespaces are named by NamespaceName by default.
+func WithNamespaceNamer(n NamespaceNamer) ObjectOption {
+	return func(o *objectOptions) {
+		o.namer = n
+	}
+}
+
+func newObjectOptions(o ...ObjectOption) *objectOptions {
+	oo := &objectOptions{namer: HashNamespaceNamer}
+	for _, fn := range o {
+		fn(oo)
+	}
+	return oo
+}
+
 // PrepareObject prepares the supplied object for submission to a remote
 // cluster by running PrepareObjectMeta on it, if possible.
-func PrepareObject(nodeName string, o runtime.Object) {
+func PrepareObject(nodeName string, o runtime.Object, oo ...ObjectOption) {
 	om, ok := o.(metav1.Object)
 	if !ok {
 		return
 	}
-	PrepareObjectMeta(nodeName, om)
+	PrepareObjectMeta(nodeName, om, oo...)
 }
 
 // PrepareObjectMeta prepares the supplied object for submission to a remote
 // cluster by adding labels that relate it back to its identity on the local
 // cluster, and removing any metadata (UIDs, etc) that would conflict with the
 // remote cluster.
-func PrepareObjectMeta(nodeName string, o metav1.Object) {
+func PrepareObjectMeta(nodeName string, o metav1.Object, oo ...ObjectOption) {
+	opts := newObjectOptions(oo...)
+
 	// Provide a hint relating the remote resource back to the local resource.
 	meta.AddLabels(o, map[string]string{
 		LabelKeyNodeName:  nodeName,
@@ -68,7 +119,7 @@ func PrepareObjectMeta(nodeName string, o metav1.Object) {
 
 	// Use a deterministic remote namespace that is scoped to the local
 	// namespace, and likely to be RFC-1123 compatible.
-	o.SetNamespace(NamespaceName(nodeName, o.GetNamespace()))
+	o.SetNamespace(opts.namer.NamespaceName(nodeName, o.GetNamespace()))
 }
 
 // RecoverObjectMeta recovers a remote object for representation in the local
@@ -99,6 +150,10 @@ func RecoverObjectMeta(o metav1.Object) {
 
 type ppo struct {
 	env []corev1.EnvVar
+	obj []ObjectOption
+
+	translateScheduling bool
+	nodeLabelKeys       map[string]bool
 }
 
 // A PreparePodOption influences how a pod is prepared for the remote cluster.
@@ -112,16 +167,41 @@ func WithEnvVars(v ...corev1.EnvVar) PreparePodOption {
 	}
 }
 
+// WithObjectOptions passes the supplied ObjectOptions through when preparing
+// the pod's object metadata.
+func WithObjectOptions(oo ...ObjectOption) PreparePodOption {
+	return func(o *ppo) {
+		o.obj = oo
+	}
+}
+
+// WithSchedulingTranslation translates the pod's scheduling constraints for
+// the remote cluster rather than removing them. Pod affinity and anti-affinity
+// terms are kept, with their namespaces rewritten to the corresponding remote
+// namespaces. Node selectors, node affinity requirements, and topology spread
+// constraints are kept only if they pertain to one of the supplied node label
+// keys, for example topology.kubernetes.io/zone.
+func WithSchedulingTranslation(nodeLabelKeys ...string) PreparePodOption {
+	return func(o *ppo) {
+		o.translateScheduling = true
+		o.nodeLabelKeys = make(map[string]bool, len(nodeLabelKeys))
+		for _, k := range nodeLabelKeys {
+			o.nodeLabelKeys[k] = true
+		}
+	}
+}
+
 // PreparePod prepares the supplied pod for submission to a remote cluster by
-// running PrepareObjectMeta on it, and removing any scheduling constraints that
-// might influence the remote cluster.
+// running PrepareObjectMeta on it, replacing projected service account tokens,
+// rewriting downward API field references, and removing (or translating) any
+// scheduling constraints that might influence the remote cluster.
 func PreparePod(nodeName string, pod *corev1.Pod, o ...PreparePodOption) {
 	ppo := &ppo{}
 	for _, fn := range o {
 		fn(ppo)
 	}
 
-	PrepareObjectMeta(nodeName, pod)
+	PrepareObjectMeta(nodeName, pod, ppo.obj...)
 
 	// Disable service account. We replicate and mount any service account token
 	// that was created on the local cluster. We don't want the remote cluster's
@@ -139,11 +219,157 @@ func PreparePod(nodeName string, pod *corev1.Pod, o ...PreparePodOption) {
 	setEnvVars(pod.Spec.InitContainers, ppo.env...)
 	setEnvVars(pod.Spec.Containers, ppo.env...)
 
-	// Remove spec fields that could influence scheduling on the remote cluster.
+	prepareServiceAccountTokenProjections(pod)
+	prepareDownwardAPI(pod)
+
+	// Ephemeral containers may not be set when a pod is created; they're added
+	// to the remote pod via its ephemeralcontainers subresource.
+	pod.Spec.EphemeralContainers = nil
+
+	// Priority, preemption policy, and overhead are resolved from the pod's
+	// priority and runtime classes by the (local) admission controllers. The
+	// remote admission controllers reject pods that specify them unless they
+	// match the remote classes, so we let them be resolved again remotely.
+	pod.Spec.Priority = nil
+	pod.Spec.PreemptionPolicy = nil
+	pod.Spec.Overhead = nil
+
+	// Remove or translate spec fields that could influence scheduling on the
+	// remote cluster.
 	pod.Spec.NodeName = ""
+	translateScheduling(nodeName, pod, ppo)
+}
+
+// translateScheduling translates or removes the spec fields of the supplied pod
+// that could influence scheduling on the remote cluster.
+func translateScheduling(nodeName string, pod *corev1.Pod, o *ppo) {
+	if !o.translateScheduling {
+		pod.Spec.NodeSelector = nil
+		pod.Spec.Affinity = nil
+		pod.Spec.TopologySpreadConstraints = nil
+		return
+	}
+
+	ns := map[string]string{}
+	for k, v := range pod.Spec.NodeSelector {
+		if o.nodeLabelKeys[k] {
+			ns[k] = v
+		}
+	}
 	pod.Spec.NodeSelector = nil
-	pod.Spec.Affinity = nil
+	if len(ns) > 0 {
+		pod.Spec.NodeSelector = ns
+	}
+
+	tscs := make([]corev1.TopologySpreadConstraint, 0, len(pod.Spec.TopologySpreadConstraints))
+	for _, tsc := range pod.Spec.TopologySpreadConstraints {
+		if o.nodeLabelKeys[tsc.TopologyKey] {
+			tscs = append(tscs, tsc)
+		}
+	}
 	pod.Spec.TopologySpreadConstraints = nil
+	if len(tscs) > 0 {
+		pod.Spec.TopologySpreadConstraints = tscs
+	}
+
+	a := pod.Spec.Affinity
+	if a == nil {
+		return
+	}
+	opts := newObjectOptions(o.obj...)
+	a.NodeAffinity = translateNodeAffinity(a.NodeAffinity, o.nodeLabelKeys)
+	if pa := a.PodAffinity; pa != nil {
+		translatePodAffinityTerms(opts.namer, nodeName, pa.RequiredDuringSchedulingIgnoredDuringExecution)
+		translateWeightedPodAffinityTerms(opts.namer, nodeName, pa.PreferredDuringSchedulingIgnoredDuringExecution)
+	}
+	if paa := a.PodAntiAffinity; paa != nil {
+		translatePodAffinityTerms(opts.namer, nodeName, paa.RequiredDuringSchedulingIgnoredDuringExecution)
+		translateWeightedPodAffinityTerms(opts.namer, nodeName, paa.PreferredDuringSchedulingIgnoredDuringExecution)
+	}
+	if a.NodeAffinity == nil && a.PodAffinity == nil && a.PodAntiAffinity == nil {
+		pod.Spec.Affinity = nil
+	}
+}
+
+// translateNodeAffinity returns the supplied node affinity, keeping only the
+// requirements that pertain to the supplied node label keys. It returns nil if
+// no requirements remain.
+//
+// Required node selector terms are ORed, and a term with no remaining
+// requirements would match no nodes. Such a term was satisfied by some local
+// node regardless of the remote node's labels, so we drop the required terms
+// entirely rather than constrain the pod to the other terms. Preferred terms
+// with no remaining requirements are simply removed.
+func translateNodeAffinity(na *corev1.NodeAffinity, keys map[string]bool) *corev1.NodeAffinity {
+	if na == nil {
+		return nil
+	}
+
+	if r := na.RequiredDuringSchedulingIgnoredDuringExecution; r != nil {
+		terms := make([]corev1.NodeSelectorTerm, 0, len(r.NodeSelectorTerms))
+		for _, t := range r.NodeSelectorTerms {
+			t, ok := translateNodeSelectorTerm(t, keys)
+			if !ok {
+				terms = nil
+				break
+			}
+			terms = append(terms, t)
+		}
+		na.RequiredDuringSchedulingIgnoredDuringExecution = nil
+		if len(terms) > 0 {
+			na.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{NodeSelectorTerms: terms}
+		}
+	}
+
+	pst := make([]corev1.PreferredSchedulingTerm, 0, len(na.PreferredDuringSchedulingIgnoredDuringExecution))
+	for _, p := range na.PreferredDuringSchedulingIgnoredDuringExecution {
+		if t, ok := translateNodeSelectorTerm(p.Preference, keys); ok {
+			pst = append(pst, corev1.PreferredSchedulingTerm{Weight: p.Weight, Preference: t})
+		}
+	}
+	na.PreferredDuringSchedulingIgnoredDuringExecution = nil
+	if len(pst) > 0 {
+		na.PreferredDuringSchedulingIgnoredDuringExecution = pst
+	}
+
+	if na.RequiredDuringSchedulingIgnoredDuringExecution == nil && na.PreferredDuringSchedulingIgnoredDuringExecution == nil {
+		return nil
+	}
+	return na
+}
+
+func translateNodeSelectorTerm(t corev1.NodeSelectorTerm, keys map[string]bool) (corev1.NodeSelectorTerm, bool) {
+	out := corev1.NodeSelectorTerm{}
+	for _, r := range t.MatchExpressions {
+		if keys[r.Key] {
+			out.MatchExpressions = append(out.MatchExpressions, r)
+		}
+	}
+	for _, r := range t.MatchFields {
+		if keys[r.Key] {
+			out.MatchFields = append(out.MatchFields, r)
+		}
+	}
+	return out, len(out.MatchExpressions) > 0 || len(out.MatchFields) > 0
+}
+
+// translatePodAffinityTerms rewrites the namespaces of the supplied terms to
+// the corresponding remote namespaces. Terms that specify no namespaces apply
+// to the pod's own namespace, and thus need no translation.
+func translatePodAffinityTerms(n NamespaceNamer, nodeName string, terms []corev1.PodAffinityTerm) {
+	for i := range terms {
+		for j, ns := range terms[i].Namespaces {
+			terms[i].Namespaces[j] = n.NamespaceName(nodeName, ns)
+		}
+	}
+}
+
+func translateWeightedPodAffinityTerms(n NamespaceNamer, nodeName string, terms []corev1.WeightedPodAffinityTerm) {
+	for i := range terms {
+		for j, ns := range terms[i].PodAffinityTerm.Namespaces {
+			terms[i].PodAffinityTerm.Namespaces[j] = n.NamespaceName(nodeName, ns)
+		}
+	}
 }
 
 func setEnvVars(cs []corev1.Container, v ...corev1.EnvVar) {
@@ -173,27 +399,191 @@ func setEnvVars(cs []corev1.Container, v ...corev1.EnvVar) {
 	}
 }
 
-// PreparePodUpdate prepares the supplied remote pod to be updated in accordance
-// with the supplied local pod. Few pod fields may be updated - currently only
-// labels and annotations are supported.
-func PreparePodUpdate(nodeName string, local, remote *corev1.Pod) {
-	// TODO(negz): Allow updating container images.
+// prepareServiceAccountTokenProjections replaces any projected service account
+// tokens with projections of the secret that contains the tokens issued by the
+// local API server. Tokens issued by the remote API server would not be valid
+// for the local API server.
+func prepareServiceAccountTokenProjections(pod *corev1.Pod) {
+	for i := range pod.Spec.Volumes {
+		v := &pod.Spec.Volumes[i]
+		if v.Projected == nil {
+			continue
+		}
+		for j := range v.Projected.Sources {
+			src := &v.Projected.Sources[j]
+			if src.ServiceAccountToken == nil {
+				continue
+			}
+			path := src.ServiceAccountToken.Path
+			src.ServiceAccountToken = nil
+			src.Secret = &corev1.SecretProjection{
+				LocalObjectReference: corev1.LocalObjectReference{Name: ServiceAccountTokenSecretName(pod.GetName(), v.Name)},
+				Items:                []corev1.KeyToPath{{Key: ServiceAccountTokenKey(path), Path: path}},
+			}
+		}
+	}
+}
+
+// downwardAPIFieldPaths maps downward API field paths that would resolve to
+// remote values to paths that resolve to the corresponding local values.
+var downwardAPIFieldPaths = map[string]string{
+	"spec.nodeName":      fmt.Sprintf("metadata.labels['%s']", LabelKeyNodeName),
+	"metadata.namespace": fmt.Sprintf("metadata.labels['%s']", LabelKeyNamespace),
+}
+
+// prepareDownwardAPI rewrites any downward API field references that would
+// resolve to remote values - i.e. the remote node and namespace - such that
+// they resolve to their local values.
+func prepareDownwardAPI(pod *corev1.Pod) {
+	cs := make([]*corev1.Container, 0, len(pod.Spec.InitContainers)+len(pod.Spec.Containers))
+	for i := range pod.Spec.InitContainers {
+		cs = append(cs, &pod.Spec.InitContainers[i])
+	}
+	for i := range pod.Spec.Containers {
+		cs = append(cs, &pod.Spec.Containers[i])
+	}
+	for _, c := range cs {
+		for i := range c.Env {
+			if c.Env[i].ValueFrom != nil {
+				setFieldPath(c.Env[i].ValueFrom.FieldRef)
+			}
+		}
+	}
+
+	for i := range pod.Spec.Volumes {
+		v := &pod.Spec.Volumes[i]
+		if v.DownwardAPI != nil {
+			for j := range v.DownwardAPI.Items {
+				setFieldPath(v.DownwardAPI.Items[j].FieldRef)
+			}
+		}
+		if v.Projected != nil {
+			for _, src := range v.Projected.Sources {
+				if src.DownwardAPI == nil {
+					continue
+				}
+				for j := range src.DownwardAPI.Items {
+					setFieldPath(src.DownwardAPI.Items[j].FieldRef)
+				}
+			}
+		}
+	}
+}
+
+func setFieldPath(s *corev1.ObjectFieldSelector) {
+	if s == nil {
+		return
+	}
+	if p, ok := downwardAPIFieldPaths[s.FieldPath]; ok {
+		s.FieldPath = p
+	}
+}
 
+// PreparePodUpdate prepares the supplied remote pod to be updated in accordance
+// with the supplied local pod. Only the fields Kubernetes allows to be updated
+// are propagated; labels, annotations, container and init container images,
+// active deadline seconds, and tolerations.
+func PreparePodUpdate(nodeName string, local, remote *corev1.Pod, oo ...ObjectOption) {
 	// Run PrepareObjectMeta on a copy of the local pod to ensure we maintain
 	// any AK-managed labels and annotations when we propagate the local pod's
 	// labels and annotations to the remote pod.
 	l := local.DeepCopy()
-	PrepareObjectMeta(nodeName, l)
+	PrepareObjectMeta(nodeName, l, oo...)
+	if n, ok := remote.GetAnnotations()[AnnotationKeyServiceAccountName]; ok {
+		meta.AddAnnotations(l, map[string]string{AnnotationKeyServiceAccountName: n})
+	}
+
+	if tc, ok := remote.GetAnnotations()[AnnotationKeyTraceContext]; ok {
+		meta.AddAnnotations(l, map[string]string{AnnotationKeyTraceContext: tc})
+	}
+
+	if n, ok := remote.GetLabels()[LabelKeyExposingService]; ok {
+		meta.AddLabels(l, map[string]string{LabelKeyExposingService: n})
+	}
 
 	remote.SetLabels(l.GetLabels())
 	remote.SetAnnotations(l.GetAnnotations())
+
+	setImages(remote.Spec.InitContainers, l.Spec.InitContainers)
+	setImages(remote.Spec.Containers, l.Spec.Containers)
+
+	remote.Spec.ActiveDeadlineSeconds = l.Spec.ActiveDeadlineSeconds
+
+	// Tolerations may only be added, not removed. The remote API server may
+	// have added tolerations that don't exist locally, so we add any local
+	// tolerations that don't exist remotely rather than replacing them.
+	for _, t := range l.Spec.Tolerations {
+		if !hasToleration(remote.Spec.Tolerations, t) {
+			remote.Spec.Tolerations = append(remote.Spec.Tolerations, t)
+		}
+	}
+}
+
+// setImages sets the image of each container in to the image of the container
+// of the same name in from.
+func setImages(to, from []corev1.Container) {
+	images := make(map[string]string, len(from))
+	for _, c := range from {
+		images[c.Name] = c.Image
+	}
+	for i := range to {
+		if img, ok := images[to[i].Name]; ok {
+			to[i].Image = img
+		}
+	}
+}
+
+// PrepareEphemeralContainers returns the ephemeral containers of the supplied
+// remote pod, plus any ephemeral containers of the supplied local pod that do
+// not yet exist remotely. It returns nil if there are no new ephemeral
+// containers. Ephemeral containers may only be added, never updated or
+// removed.
+func PrepareEphemeralContainers(local, remote *corev1.Pod) *corev1.EphemeralContainers {
+	exists := make(map[string]bool, len(remote.Spec.EphemeralContainers))
+	for _, ec := range remote.Spec.EphemeralContainers {
+		exists[ec.Name] = true
+	}
+
+	ecs := &corev1.EphemeralContainers{
+		ObjectMeta:          metav1.ObjectMeta{Namespace: remote.GetNamespace(), Name: remote.GetName(), ResourceVersion: remote.GetResourceVersion()},
+		EphemeralContainers: remote.DeepCopy().Spec.EphemeralContainers,
+	}
+	for _, ec := range local.Spec.EphemeralContainers {
+		if exists[ec.Name] {
+			continue
+		}
+		ec = *ec.DeepCopy()
+		for i := range ec.Env {
+			if ec.Env[i].ValueFrom != nil {
+				setFieldPath(ec.Env[i].ValueFrom.FieldRef)
+			}
+		}
+		ecs.EphemeralContainers = append(ecs.EphemeralContainers, ec)
+	}
+
+	if len(ecs.EphemeralContainers) == len(remote.Spec.EphemeralContainers) {
+		return nil
+	}
+	return ecs
+}
+
+func hasToleration(ts []corev1.Toleration, t corev1.Toleration) bool {
+	for i := range ts {
+		if ts[i].MatchToleration(&t) && reflect.DeepEqual(ts[i].TolerationSeconds, t.TolerationSeconds) {
+			return true
+		}
+	}
+	return false
 }
 
 // RecoverPod recovers the supplied pod for representation in the local cluster
 // by running RecoverObjectMeta on it, and removing any scheduling constraints
-// that might influence the local cluster.
+// that might influence the local cluster. Pod status, including the status of
+// any ephemeral containers, is reported as is.
 func RecoverPod(pod *corev1.Pod) {
 	RecoverObjectMeta(pod)
+	delete(pod.GetLabels(), LabelKeyExposingService)
+	delete(pod.GetAnnotations(), AnnotationKeyTraceContext)
 
 	pod.Spec.NodeName = ""
 	pod.Spec.NodeSelector = nil
@@ -206,10 +596,11 @@ func RecoverPod(pod *corev1.Pod) {
 // many (local) virtual kubelets to create pods (and their dependencies) in one
 // remote cluster. Each remote namespace corresponds to a single local namespace
 // as long as all Kubelet node names are unique within the remote cluster.
-func Namespace(nodeName, localNamespace string) *corev1.Namespace {
+func Namespace(nodeName, localNamespace string, oo ...ObjectOption) *corev1.Namespace {
+	opts := newObjectOptions(oo...)
 	ns := &corev1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: NamespaceName(nodeName, localNamespace),
+			Name: opts.namer.NamespaceName(nodeName, localNamespace),
 			Labels: map[string]string{
 				LabelKeyNodeName:  nodeName,
 				LabelKeyNamespace: localNamespace,
@@ -220,17 +611,202 @@ func Namespace(nodeName, localNamespace string) *corev1.Namespace {
 	return ns
 }
 
+// ResourceQuota returns a remote resource quota enforcing the supplied hard
+// limits in the remote namespace corresponding to the supplied local namespace.
+func ResourceQuota(nodeName, localNamespace string, hard corev1.ResourceList, oo ...ObjectOption) *corev1.ResourceQuota {
+	opts := newObjectOptions(oo...)
+	return &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: opts.namer.NamespaceName(nodeName, localNamespace),
+			Name:      NamespaceQuotaName,
+			Labels: map[string]string{
+				LabelKeyNodeName:  nodeName,
+				LabelKeyNamespace: localNamespace,
+			},
+		},
+		Spec: corev1.ResourceQuotaSpec{Hard: hard},
+	}
+}
+
+// LimitRange returns a remote limit range enforcing the supplied per-container
+// limits in the remote namespace corresponding to the supplied local namespace.
+func LimitRange(nodeName, localNamespace string, limits corev1.LimitRangeItem, oo ...ObjectOption) *corev1.LimitRange {
+	opts := newObjectOptions(oo...)
+	limits.Type = corev1.LimitTypeContainer
+	return &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: opts.namer.NamespaceName(nodeName, localNamespace),
+			Name:      NamespaceQuotaName,
+			Labels: map[string]string{
+				LabelKeyNodeName:  nodeName,
+				LabelKeyNamespace: localNamespace,
+			},
+		},
+		Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{limits}},
+	}
+}
+
 // NamespaceName returns a remote namespace name. Remote namespaces are named
 // such that each remote namespace corresponds to a single local namespace as
 // long as all Kubelet node names are unique within the remote cluster.
 func NamespaceName(nodeName, localNamespace string) string {
+	return truncate(fmt.Sprintf("%s-%s", nodeName, hash64(localNamespace)))
+}
+
+// ReadableNamespaceName returns a human readable remote namespace name of the
+// form <node-name>-<local-namespace>-<hash>. The hash is of the node name and
+// local namespace pair; without it node "a-b" and namespace "c" would share a
+// remote namespace with node "a" and namespace "b-c". Node names may contain
+// dots, which namespace names may not, so dots are replaced with hyphens; the
+// hash keeps node "a.b" distinct from node "a-b". Names that would be longer
+// than a namespace name may be are truncated before the hash.
+func ReadableNamespaceName(nodeName, localNamespace string) string {
+	suffix := "-" + hash32(nodeName+"/"+localNamespace)
+	name := strings.ReplaceAll(nodeName, ".", "-") + "-" + localNamespace
+	return trim(validation.DNS1123LabelMaxLength-len(suffix), name) + suffix
+}
+
+// A NamespaceNamer names the remote namespace corresponding to a local
+// namespace. A NamespaceNamer must return a different name for each node name
+// and local namespace combination. The node name and local namespace of a
+// remote namespace are recorded as labels, so a remote namespace need not be
+// named such that they can be derived from its name.
+type NamespaceNamer interface {
+	NamespaceName(nodeName, localNamespace string) string
+}
+
+// A NamespaceNamerFn names the remote namespace corresponding to a local
+// namespace.
+type NamespaceNamerFn func(nodeName, localNamespace string) string
+
+// NamespaceName returns the remote namespace name corresponding to the supplied
+// node name and local namespace.
+func (fn NamespaceNamerFn) NamespaceName(nodeName, localNamespace string) string {
+	return fn(nodeName, localNamespace)
+}
+
+var (
+	// HashNamespaceNamer names remote namespaces using NamespaceName.
+	HashNamespaceNamer NamespaceNamer = NamespaceNamerFn(NamespaceName)
+
+	// ReadableNamespaceNamer names remote namespaces using
+	// ReadableNamespaceName.
+	ReadableNamespaceNamer NamespaceNamer = NamespaceNamerFn(ReadableNamespaceName)
+)
+
+// NamespaceNameData is passed to the template used by a
+// TemplateNamespaceNamer.
+type NamespaceNameData struct {
+	// NodeName of the Virtual Kubelet.
+	NodeName string
+
+	// Namespace is the local namespace.
+	Namespace string
+
+	// Hash of the local namespace.
+	Hash string
+}
+
+// A TemplateNamespaceNamer names remote namespaces using a Go template.
+type TemplateNamespaceNamer struct {
+	t *template.Template
+}
+
+// NewTemplateNamespaceNamer returns a NamespaceNamer that names remote
+// namespaces by executing the supplied Go template with NamespaceNameData.
+// Names that would be longer than a namespace name may be are truncated and
+// suffixed with a hash of the full name. Templates that don't produce a valid
+// namespace name, or that don't produce a different name for each node name
+// and local namespace, are rejected.
+func NewTemplateNamespaceNamer(tmpl string) (*TemplateNamespaceNamer, error) {
+	t, err := template.New("namespace").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot parse namespace name template")
+	}
+	n := &TemplateNamespaceNamer{t: t}
+
+	// Execute the template up front so that templates that reference fields
+	// that don't exist or produce invalid names are rejected early.
+	names := map[string]bool{}
+	for _, d := range [][2]string{{"node-a", "default"}, {"node-a", "kube-system"}, {"node-b", "default"}} {
+		name, err := n.execute(d[0], d[1])
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot execute namespace name template")
+		}
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			return nil, errors.Errorf("namespace name template produced invalid name %q: %s", name, strings.Join(errs, ", "))
+		}
+		names[name] = true
+	}
+	if len(names) != 3 {
+		return nil, errors.New("namespace name template must produce a different name for each node name and namespace")
+	}
+
+	return n, nil
+}
+
+func (n *TemplateNamespaceNamer) execute(nodeName, localNamespace string) (string, error) {
+	b := &strings.Builder{}
+	d := NamespaceNameData{NodeName: nodeName, Namespace: localNamespace, Hash: hash64(localNamespace)}
+	if err := n.t.Execute(b, d); err != nil {
+		return "", err
+	}
+	return truncate(b.String()), nil
+}
+
+// NamespaceName returns the remote namespace name corresponding to the supplied
+// node name and local namespace. NewTemplateNamespaceNamer ensures the template
+// can be executed, but the name returned by NamespaceName is used if it can't,
+// or if it produces an invalid name for this node name and local namespace.
+func (n *TemplateNamespaceNamer) NamespaceName(nodeName, localNamespace string) string {
+	name, err := n.execute(nodeName, localNamespace)
+	if err != nil || len(validation.IsDNS1123Label(name)) > 0 {
+		return NamespaceName(nodeName, localNamespace)
+	}
+	return name
+}
+
+func hash64(s string) string {
 	h := fnv.New64()
-	_, _ = h.Write([]byte(localNamespace)) // Writing to a hash never errors.
-	return fmt.Sprintf("%s-%x", nodeName, h.Sum64())
+	_, _ = h.Write([]byte(s)) // Writing to a hash never errors.
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// truncate the supplied name to the maximum length of a namespace name.
+func truncate(name string) string {
+	return truncateTo(validation.DNS1123LabelMaxLength, name)
+}
+
+// truncateTo truncates the supplied name to the supplied length. Names that
+// are too long are suffixed with a hash of the full name in order to keep them
+// unique.
+func truncateTo(length int, name string) string {
+	if len(name) <= length {
+		return name
+	}
+	suffix := "-" + hash32(name)
+	return trim(length-len(suffix), name) + suffix
+}
+
+// trim the supplied name to the supplied length, removing any trailing
+// characters that may not end a name.
+func trim(length int, name string) string {
+	if len(name) <= length {
+		return name
+	}
+	return strings.TrimRight(name[:length], "-.")
+}
+
+func hash32(s string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(s)) // Writing to a hash never errors.
+	return fmt.Sprintf("%08x", h.Sum32())
 }
 
 // IsTokenVolume returns true if the supplied volume is (very likely to be) a
-// service account token volume.
+// legacy service account token volume. Kubernetes 1.24 and later no longer
+// create legacy token secrets; pods instead use a projected service account
+// token. See HasServiceAccountTokenProjection.
 func IsTokenVolume(v corev1.Volume) bool {
 	// TODO(negz): We can probably raise our confidence that this is a
 	// token volume by checking that the SecretVolumeSource's DefaultMode is
@@ -244,6 +820,115 @@ func IsTokenVolume(v corev1.Volume) bool {
 	return strings.Contains(v.Secret.SecretName, "-token-")
 }
 
+// Annotations that the local persistent volume controller and scheduler add to
+// a persistent volume claim. They describe the claim's binding within the local
+// cluster, and would confuse the remote cluster.
+var localClaimAnnotations = []string{
+	"pv.kubernetes.io/bind-completed",
+	"pv.kubernetes.io/bound-by-controller",
+	"volume.beta.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/selected-node",
+}
+
+// ClassName returns the remote name of a replicated cluster scoped class, such
+// as a PriorityClass or RuntimeClass. Replicated classes are prefixed with the
+// node name so that classes replicated by different nodes don't conflict.
+// PriorityClasses with the reserved "system-" prefix exist in every cluster;
+// they are never replicated, and retain their name.
+func ClassName(nodeName, name string) string {
+	if IsSystemClass(name) {
+		return name
+	}
+	return truncateTo(validation.DNS1123SubdomainMaxLength, fmt.Sprintf("%s-%s", nodeName, name))
+}
+
+// IsSystemClass returns true if the supplied class name uses the "system-"
+// prefix reserved for classes built in to every cluster.
+func IsSystemClass(name string) bool {
+	return strings.HasPrefix(name, "system-")
+}
+
+// prepareClassMeta prepares the supplied cluster scoped class for submission to
+// a remote cluster by naming it per ClassName, adding a label relating it back
+// to the node that replicated it, and removing any metadata that would conflict
+// with the remote cluster.
+func prepareClassMeta(nodeName string, o metav1.Object) {
+	o.SetName(ClassName(nodeName, o.GetName()))
+	meta.AddLabels(o, map[string]string{LabelKeyNodeName: nodeName})
+	o.SetUID(types.UID(""))
+	o.SetResourceVersion("")
+	o.SetSelfLink("")
+	o.SetOwnerReferences(nil)
+}
+
+// PreparePriorityClass prepares the supplied priority class for submission to
+// a remote cluster. A replicated priority class is never the remote cluster's
+// global default.
+func PreparePriorityClass(nodeName string, pc *schedulingv1.PriorityClass) {
+	prepareClassMeta(nodeName, pc)
+	pc.GlobalDefault = false
+}
+
+// PrepareRuntimeClass prepares the supplied runtime class for submission to a
+// remote cluster.
+func PrepareRuntimeClass(nodeName string, rc *nodev1beta1.RuntimeClass) {
+	prepareClassMeta(nodeName, rc)
+}
+
+// PreparePersistentVolumeClaim prepares the supplied persistent volume claim
+// for submission to a remote cluster by removing any details of how it is
+// bound in the local cluster, and mapping its storage class. The supplied
+// classes map local storage class names to remote storage class names. Storage
+// classes that do not appear in the map are left as is.
+func PreparePersistentVolumeClaim(c *corev1.PersistentVolumeClaim, classes map[string]string) {
+	a := map[string]string{}
+	for k, v := range c.GetAnnotations() {
+		a[k] = v
+	}
+	for _, k := range localClaimAnnotations {
+		delete(a, k)
+	}
+	c.SetAnnotations(a)
+	c.SetFinalizers(nil)
+
+	// The remote claim will be bound to a remote volume by the remote cluster.
+	c.Spec.VolumeName = ""
+	c.Spec.Selector = nil
+	if sc := c.Spec.StorageClassName; sc != nil {
+		if mapped, ok := classes[*sc]; ok {
+			c.Spec.StorageClassName = &mapped
+		}
+	}
+
+	c.Status = corev1.PersistentVolumeClaimStatus{}
+}
+
+// HasServiceAccountTokenProjection returns true if the supplied volume is a
+// projected volume with at least one service account token source.
+func HasServiceAccountTokenProjection(v corev1.Volume) bool {
+	if v.Projected == nil {
+		return false
+	}
+	for _, src := range v.Projected.Sources {
+		if src.ServiceAccountToken != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceAccountTokenSecretName returns the name of the secret that contains
+// the service account tokens issued for the supplied pod's projected volume.
+func ServiceAccountTokenSecretName(podName, volumeName string) string {
+	return truncateTo(validation.DNS1123SubdomainMaxLength, fmt.Sprintf("%s-%s-token", podName, volumeName))
+}
+
+// ServiceAccountTokenKey returns the key of the service account token that is
+// projected to the supplied path within a ServiceAccountTokenSecretName secret.
+func ServiceAccountTokenKey(path string) string {
+	return strings.ReplaceAll(path, "/", ".")
+}
+
 // PrepareServiceAccountTokenSecret updates the type and annotations of a
 // service account secret. This ensures the remote cluster's service account
 // controller does not attempt to garbage collect or otherwise interfere with
@@ -264,3 +949,111 @@ func PrepareServiceAccountTokenSecret(s *corev1.Secret) {
 	s.SetAnnotations(a)
 	s.Type = SecretTypeReplicatedServiceAccountToken
 }
+
+// PrepareService prepares the supplied local service to be mirrored to a remote
+// cluster. The mirrored service has no selector; it would otherwise select the
+// remote pods in its namespace rather than the local service's backends. If an
+// external name is supplied the mirrored service is an ExternalName service
+// that resolves to it. Otherwise it is a ClusterIP service (or a headless
+// service, if the local service is headless) whose endpoints are expected to be
+// mirrored from the local service's endpoints.
+func PrepareService(s *corev1.Service, externalName string) {
+	s.Spec.Selector = nil
+	s.Spec.ExternalIPs = nil
+	s.Spec.LoadBalancerIP = ""
+	s.Spec.LoadBalancerSourceRanges = nil
+	s.Spec.ExternalTrafficPolicy = ""
+	s.Spec.HealthCheckNodePort = 0
+	s.Spec.TopologyKeys = nil
+	for i := range s.Spec.Ports {
+		s.Spec.Ports[i].NodePort = 0
+	}
+
+	// Remote cluster IPs are allocated by the remote cluster.
+	if s.Spec.ClusterIP != corev1.ClusterIPNone {
+		s.Spec.ClusterIP = ""
+	}
+
+	s.Spec.Type = corev1.ServiceTypeClusterIP
+	s.Spec.ExternalName = ""
+	if externalName != "" {
+		s.Spec.Type = corev1.ServiceTypeExternalName
+		s.Spec.ExternalName = externalName
+		s.Spec.ClusterIP = ""
+	}
+
+	s.Status = corev1.ServiceStatus{}
+}
+
+// PrepareEndpoints prepares the supplied local endpoints to be mirrored to a
+// remote cluster by removing references to the local pods and nodes that back
+// them.
+func PrepareEndpoints(e *corev1.Endpoints) {
+	for i := range e.Subsets {
+		ss := &e.Subsets[i]
+		for j := range ss.Addresses {
+			ss.Addresses[j].NodeName = nil
+			ss.Addresses[j].TargetRef = nil
+		}
+		for j := range ss.NotReadyAddresses {
+			ss.NotReadyAddresses[j].NodeName = nil
+			ss.NotReadyAddresses[j].TargetRef = nil
+		}
+	}
+}
+
+// ExposingServiceName returns the name of the remote service that exposes the
+// supplied pod. Each pod is exposed by its own service, unless a label key to
+// group pods by is supplied. Pods with the same value for that label are
+// exposed by the same service.
+func ExposingServiceName(pod *corev1.Pod, groupBy string) string {
+	if v, ok := pod.GetLabels()[groupBy]; ok && groupBy != "" {
+		return "ak-group-" + hash64(groupBy+"="+v)
+	}
+	return "ak-pod-" + hash64(pod.GetName())
+}
+
+// ExposingService returns a remote service of the supplied type that exposes
+// all container ports of the supplied remote pod at the same port numbers, such
+// that they're reachable at the service's address as they would be at the
+// pod's IP. The pod must be labelled with LabelKeyExposingService.
+func ExposingService(pod *corev1.Pod, t corev1.ServiceType) *corev1.Service {
+	name := pod.GetLabels()[LabelKeyExposingService]
+	s := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: pod.GetNamespace(),
+			Name:      name,
+			Labels: map[string]string{
+				LabelKeyNodeName:  pod.GetLabels()[LabelKeyNodeName],
+				LabelKeyNamespace: pod.GetLabels()[LabelKeyNamespace],
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:     t,
+			Selector: map[string]string{LabelKeyExposingService: name},
+		},
+	}
+
+	exists := map[string]bool{}
+	for _, c := range pod.Spec.Containers {
+		for _, cp := range c.Ports {
+			proto := cp.Protocol
+			if proto == "" {
+				proto = corev1.ProtocolTCP
+			}
+			pn := fmt.Sprintf("%s-%d", strings.ToLower(string(proto)), cp.ContainerPort)
+			if exists[pn] {
+				continue
+			}
+			exists[pn] = true
+			s.Spec.Ports = append(s.Spec.Ports, corev1.ServicePort{
+				Name:       pn,
+				Protocol:   proto,
+				Port:       cp.ContainerPort,
+				TargetPort: intstr.FromInt(int(cp.ContainerPort)),
+			})
+		}
+	}
+
+	return s
+}
@@ -5,9 +5,11 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 const (
@@ -165,6 +167,7 @@ func TestPreparePod(t *testing.T) {
 	svcAcctName := "acct"
 	envVar := "var"
 	envVal := "val"
+	zone := "topology.kubernetes.io/zone"
 
 	type args struct {
 		nodeName string
@@ -243,6 +246,198 @@ func TestPreparePod(t *testing.T) {
 				},
 			},
 		},
+		"ProjectedVolumesAndDownwardAPI": {
+			reason: "Projected service account tokens should be replaced with secrets, and downward API field references should resolve to local values",
+			args: args{
+				nodeName: nodeName,
+				pod: &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: nsName,
+						Name:      name,
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{
+							Env: []corev1.EnvVar{{
+								Name:      "NODE",
+								ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"}},
+							}},
+						}},
+						Volumes: []corev1.Volume{{
+							Name: "coolvolume",
+							VolumeSource: corev1.VolumeSource{
+								Projected: &corev1.ProjectedVolumeSource{
+									Sources: []corev1.VolumeProjection{
+										{ServiceAccountToken: &corev1.ServiceAccountTokenProjection{Path: "token"}},
+										{DownwardAPI: &corev1.DownwardAPIProjection{Items: []corev1.DownwardAPIVolumeFile{{
+											Path:     "namespace",
+											FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"},
+										}}}},
+									},
+								},
+							},
+						}},
+					},
+				},
+			},
+			want: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: nodeName + nsNameHash,
+					Name:      name,
+					Labels: map[string]string{
+						LabelKeyNamespace: nsName,
+						LabelKeyNodeName:  nodeName,
+					},
+				},
+				Spec: corev1.PodSpec{
+					AutomountServiceAccountToken: func() *bool {
+						f := false
+						return &f
+					}(),
+					Containers: []corev1.Container{{
+						Env: []corev1.EnvVar{{
+							Name:      "NODE",
+							ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.labels['" + LabelKeyNodeName + "']"}},
+						}},
+					}},
+					Volumes: []corev1.Volume{{
+						Name: "coolvolume",
+						VolumeSource: corev1.VolumeSource{
+							Projected: &corev1.ProjectedVolumeSource{
+								Sources: []corev1.VolumeProjection{
+									{Secret: &corev1.SecretProjection{
+										LocalObjectReference: corev1.LocalObjectReference{Name: name + "-coolvolume-token"},
+										Items:                []corev1.KeyToPath{{Key: "token", Path: "token"}},
+									}},
+									{DownwardAPI: &corev1.DownwardAPIProjection{Items: []corev1.DownwardAPIVolumeFile{{
+										Path:     "namespace",
+										FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.labels['" + LabelKeyNamespace + "']"},
+									}}}},
+								},
+							},
+						},
+					}},
+				},
+			},
+		},
+		"TranslatedScheduling": {
+			reason: "Pod affinity namespaces should be translated, and only node constraints pertaining to allowed label keys kept",
+			args: args{
+				nodeName: nodeName,
+				pod: &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: nsName,
+						Name:      name,
+					},
+					Spec: corev1.PodSpec{
+						NodeName:     "localnode",
+						NodeSelector: map[string]string{zone: "a", "pool": "local"},
+						Affinity: &corev1.Affinity{
+							NodeAffinity: &corev1.NodeAffinity{
+								RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{
+									{MatchExpressions: []corev1.NodeSelectorRequirement{
+										{Key: zone, Operator: corev1.NodeSelectorOpIn, Values: []string{"c"}},
+										{Key: "pool", Operator: corev1.NodeSelectorOpExists},
+									}},
+									{MatchExpressions: []corev1.NodeSelectorRequirement{
+										{Key: zone, Operator: corev1.NodeSelectorOpIn, Values: []string{"a", "b"}},
+										{Key: "pool", Operator: corev1.NodeSelectorOpExists},
+									}},
+								}},
+								PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{{
+									Weight:     1,
+									Preference: corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpExists}}},
+								}},
+							},
+							PodAntiAffinity: &corev1.PodAntiAffinity{
+								RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+									{TopologyKey: zone},
+									{TopologyKey: zone, Namespaces: []string{"otherns"}},
+								},
+							},
+						},
+						TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+							{MaxSkew: 1, TopologyKey: zone},
+							{MaxSkew: 1, TopologyKey: "pool"},
+						},
+					},
+				},
+				o: []PreparePodOption{WithSchedulingTranslation(zone)},
+			},
+			want: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: nodeName + nsNameHash,
+					Name:      name,
+					Labels: map[string]string{
+						LabelKeyNamespace: nsName,
+						LabelKeyNodeName:  nodeName,
+					},
+				},
+				Spec: corev1.PodSpec{
+					AutomountServiceAccountToken: func() *bool {
+						f := false
+						return &f
+					}(),
+					NodeSelector: map[string]string{zone: "a"},
+					Affinity: &corev1.Affinity{
+						NodeAffinity: &corev1.NodeAffinity{
+							RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{
+								{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: zone, Operator: corev1.NodeSelectorOpIn, Values: []string{"c"}}}},
+								{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: zone, Operator: corev1.NodeSelectorOpIn, Values: []string{"a", "b"}}}},
+							}},
+						},
+						PodAntiAffinity: &corev1.PodAntiAffinity{
+							RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+								{TopologyKey: zone},
+								{TopologyKey: zone, Namespaces: []string{NamespaceName(nodeName, "otherns")}},
+							},
+						},
+					},
+					TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{MaxSkew: 1, TopologyKey: zone}},
+				},
+			},
+		},
+		"UnconstrainedNodeSelectorTerm": {
+			reason: "Required node selector terms should be dropped if any term has no requirements pertaining to allowed label keys",
+			args: args{
+				nodeName: nodeName,
+				pod: &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: nsName,
+						Name:      name,
+					},
+					Spec: corev1.PodSpec{
+						Affinity: &corev1.Affinity{
+							NodeAffinity: &corev1.NodeAffinity{
+								RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{
+									{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "pool", Operator: corev1.NodeSelectorOpExists}}},
+									{MatchExpressions: []corev1.NodeSelectorRequirement{
+										{Key: zone, Operator: corev1.NodeSelectorOpIn, Values: []string{"a", "b"}},
+										{Key: "pool", Operator: corev1.NodeSelectorOpExists},
+									}},
+								}},
+							},
+						},
+					},
+				},
+				o: []PreparePodOption{WithSchedulingTranslation(zone)},
+			},
+			want: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: nodeName + nsNameHash,
+					Name:      name,
+					Labels: map[string]string{
+						LabelKeyNamespace: nsName,
+						LabelKeyNodeName:  nodeName,
+					},
+				},
+				Spec: corev1.PodSpec{
+					AutomountServiceAccountToken: func() *bool {
+						f := false
+						return &f
+					}(),
+				},
+			},
+		},
 	}
 
 	for name, tc := range cases {
@@ -258,6 +453,7 @@ func TestPreparePod(t *testing.T) {
 func TestPreparePodUpdate(t *testing.T) {
 	labels := map[string]string{"l": "t"}
 	annos := map[string]string{"a": "t"}
+	deadline := int64(42)
 
 	type args struct {
 		nodeName string
@@ -308,6 +504,56 @@ func TestPreparePodUpdate(t *testing.T) {
 				},
 			},
 		},
+		"PodSpec": {
+			reason: "The remote pod's images and active deadline should be updated, and tolerations added",
+			args: args{
+				nodeName: nodeName,
+				local: &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Namespace: nsName, Name: name},
+					Spec: corev1.PodSpec{
+						ActiveDeadlineSeconds: &deadline,
+						InitContainers:        []corev1.Container{{Name: "init", Image: "init:v2"}},
+						Containers:            []corev1.Container{{Name: "app", Image: "app:v2"}},
+						Tolerations:           []corev1.Toleration{{Key: "local"}},
+					},
+				},
+				remote: &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: nodeName + nsNameHash,
+						Name:      name,
+						Annotations: map[string]string{
+							AnnotationKeyServiceAccountName: "sa",
+							AnnotationKeyTraceContext:       "00-cool-trace-01",
+						},
+					},
+					Spec: corev1.PodSpec{
+						InitContainers: []corev1.Container{{Name: "init", Image: "init:v1"}},
+						Containers:     []corev1.Container{{Name: "app", Image: "app:v1"}},
+						Tolerations:    []corev1.Toleration{{Key: "remote"}},
+					},
+				},
+			},
+			want: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: nodeName + nsNameHash,
+					Name:      name,
+					Labels: map[string]string{
+						LabelKeyNamespace: nsName,
+						LabelKeyNodeName:  nodeName,
+					},
+					Annotations: map[string]string{
+						AnnotationKeyServiceAccountName: "sa",
+						AnnotationKeyTraceContext:       "00-cool-trace-01",
+					},
+				},
+				Spec: corev1.PodSpec{
+					ActiveDeadlineSeconds: &deadline,
+					InitContainers:        []corev1.Container{{Name: "init", Image: "init:v2"}},
+					Containers:            []corev1.Container{{Name: "app", Image: "app:v2"}},
+					Tolerations:           []corev1.Toleration{{Key: "remote"}, {Key: "local"}},
+				},
+			},
+		},
 	}
 
 	for name, tc := range cases {
@@ -320,6 +566,75 @@ func TestPreparePodUpdate(t *testing.T) {
 	}
 }
 
+func TestPrepareEphemeralContainers(t *testing.T) {
+	ec := func(name string) corev1.EphemeralContainer {
+		return corev1.EphemeralContainer{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: name}}
+	}
+	rmt := func(ecs ...corev1.EphemeralContainer) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: nodeName + nsNameHash, Name: name, ResourceVersion: "1"},
+			Spec:       corev1.PodSpec{EphemeralContainers: ecs},
+		}
+	}
+
+	type args struct {
+		local  *corev1.Pod
+		remote *corev1.Pod
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   *corev1.EphemeralContainers
+	}{
+		"NoNewEphemeralContainers": {
+			reason: "Nil should be returned if all local ephemeral containers exist remotely",
+			args: args{
+				local:  &corev1.Pod{Spec: corev1.PodSpec{EphemeralContainers: []corev1.EphemeralContainer{ec("debug")}}},
+				remote: rmt(ec("debug")),
+			},
+			want: nil,
+		},
+		"NewEphemeralContainers": {
+			reason: "New local ephemeral containers should be appended to the remote pod's, with downward API references rewritten",
+			args: args{
+				local: &corev1.Pod{Spec: corev1.PodSpec{EphemeralContainers: []corev1.EphemeralContainer{
+					ec("debug"),
+					{EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+						Name: "debug-2",
+						Env: []corev1.EnvVar{{
+							Name:      "NODE",
+							ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"}},
+						}},
+					}},
+				}}},
+				remote: rmt(ec("debug")),
+			},
+			want: &corev1.EphemeralContainers{
+				ObjectMeta: metav1.ObjectMeta{Namespace: nodeName + nsNameHash, Name: name, ResourceVersion: "1"},
+				EphemeralContainers: []corev1.EphemeralContainer{
+					ec("debug"),
+					{EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+						Name: "debug-2",
+						Env: []corev1.EnvVar{{
+							Name:      "NODE",
+							ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.labels['" + LabelKeyNodeName + "']"}},
+						}},
+					}},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := PrepareEphemeralContainers(tc.args.local, tc.args.remote)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nPrepareEphemeralContainers(...): -want, +got: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
 func TestRecoverPod(t *testing.T) {
 	cases := map[string]struct {
 		reason string
@@ -373,6 +688,7 @@ func TestNamespace(t *testing.T) {
 	type args struct {
 		nodeName       string
 		localNamespace string
+		o              []ObjectOption
 	}
 	cases := map[string]struct {
 		reason string
@@ -395,11 +711,28 @@ func TestNamespace(t *testing.T) {
 				},
 			},
 		},
+		"NamespaceNamer": {
+			reason: "The supplied NamespaceNamer should be used to name the namespace",
+			args: args{
+				nodeName:       nodeName,
+				localNamespace: localName,
+				o:              []ObjectOption{WithNamespaceNamer(ReadableNamespaceNamer)},
+			},
+			want: &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: nodeName + "-" + localName + "-c1441acf",
+					Labels: map[string]string{
+						LabelKeyNodeName:  nodeName,
+						LabelKeyNamespace: localName,
+					},
+				},
+			},
+		},
 	}
 
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			got := Namespace(tc.args.nodeName, tc.args.localNamespace)
+			got := Namespace(tc.args.nodeName, tc.args.localNamespace, tc.args.o...)
 			if diff := cmp.Diff(tc.want, got); diff != "" {
 				t.Errorf("\n%s\nNamespace(...): -want, +got: \n%s\n", tc.reason, diff)
 			}
@@ -407,6 +740,215 @@ func TestNamespace(t *testing.T) {
 	}
 }
 
+func TestPreparePriorityClass(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		pc     *schedulingv1.PriorityClass
+		want   *schedulingv1.PriorityClass
+	}{
+		"PriorityClass": {
+			reason: "Priority classes should be prefixed with the node name, and never be the global default",
+			pc: &schedulingv1.PriorityClass{
+				ObjectMeta:    metav1.ObjectMeta{Name: "high", UID: types.UID("cool-uid"), ResourceVersion: "42"},
+				Value:         1000,
+				GlobalDefault: true,
+			},
+			want: &schedulingv1.PriorityClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   nodeName + "-high",
+					Labels: map[string]string{LabelKeyNodeName: nodeName},
+				},
+				Value: 1000,
+			},
+		},
+		"SystemPriorityClass": {
+			reason: "System priority classes should not be renamed",
+			pc:     &schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: "system-node-critical"}},
+			want: &schedulingv1.PriorityClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "system-node-critical",
+					Labels: map[string]string{LabelKeyNodeName: nodeName},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			PreparePriorityClass(nodeName, tc.pc)
+			if diff := cmp.Diff(tc.want, tc.pc); diff != "" {
+				t.Errorf("\n%s\nPreparePriorityClass(...): -want, +got: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNamespaceName(t *testing.T) {
+	type args struct {
+		nodeName       string
+		localNamespace string
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   string
+	}{
+		"Short": {
+			reason: "Names that fit within a namespace name should be used as is",
+			args: args{
+				nodeName:       nodeName,
+				localNamespace: nsName,
+			},
+			want: nodeName + nsNameHash,
+		},
+		"Long": {
+			reason: "Names that do not fit within a namespace name should be truncated and suffixed with a hash",
+			args: args{
+				nodeName:       "a-very-long-node-name-that-is-used-by-a-virtual-kubelet-in-a-cool-cluster",
+				localNamespace: nsName,
+			},
+			want: "a-very-long-node-name-that-is-used-by-a-virtual-kubele-2ae8110c",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := NamespaceName(tc.args.nodeName, tc.args.localNamespace)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nNamespaceName(...): -want, +got: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReadableNamespaceName(t *testing.T) {
+	type args struct {
+		nodeName       string
+		localNamespace string
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   string
+	}{
+		"Short": {
+			reason: "Names that fit within a namespace name should be suffixed with a hash of the node name and namespace",
+			args: args{
+				nodeName:       "coolnode",
+				localNamespace: "coolns",
+			},
+			want: "coolnode-coolns-c1441acf",
+		},
+		"Long": {
+			reason: "Names that do not fit within a namespace name should be truncated before the hash",
+			args: args{
+				nodeName:       "a-very-long-node-name-that-is-used-by-a-virtual-kubelet-in-a-cool-cluster",
+				localNamespace: "coolns",
+			},
+			want: "a-very-long-node-name-that-is-used-by-a-virtual-kubele-b6a68a47",
+		},
+		"AmbiguousNodeName": {
+			reason: "A node name containing a hyphen should not produce the same name as another node and namespace",
+			args: args{
+				nodeName:       "a-b",
+				localNamespace: "c",
+			},
+			want: "a-b-c-508f0545",
+		},
+		"AmbiguousNamespace": {
+			reason: "A namespace containing a hyphen should not produce the same name as another node and namespace",
+			args: args{
+				nodeName:       "a",
+				localNamespace: "b-c",
+			},
+			want: "a-b-c-3f7971a5",
+		},
+		"DottedNodeName": {
+			reason: "Dots in a node name should be replaced, since they're not valid in a namespace name",
+			args: args{
+				nodeName:       "node.example.com",
+				localNamespace: "coolns",
+			},
+			want: "node-example-com-coolns-0c7a3af5",
+		},
+		"LongDottedNodeName": {
+			reason: "Names with dots that do not fit within a namespace name should be replaced and truncated before the hash",
+			args: args{
+				nodeName:       "a-very-long.node-name.that-is-used.by-a-virtual-kubelet.in-a-cool-cluster",
+				localNamespace: "coolns",
+			},
+			want: "a-very-long-node-name-that-is-used-by-a-virtual-kubele-310b03e1",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ReadableNamespaceName(tc.args.nodeName, tc.args.localNamespace)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nReadableNamespaceName(...): -want, +got: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestTemplateNamespaceNamer(t *testing.T) {
+	type want struct {
+		name string
+		err  bool
+	}
+	cases := map[string]struct {
+		reason string
+		tmpl   string
+		want   want
+	}{
+		"Valid": {
+			reason: "A valid template should be used to name the namespace",
+			tmpl:   "ak-{{ .Namespace }}-{{ .NodeName }}",
+			want:   want{name: "ak-" + nsName + "-" + nodeName},
+		},
+		"Hash": {
+			reason: "A template should be able to use the hash of the local namespace",
+			tmpl:   "{{ .NodeName }}-{{ .Hash }}",
+			want:   want{name: nodeName + nsNameHash},
+		},
+		"InvalidName": {
+			reason: "A template that produces an invalid namespace name should be rejected",
+			tmpl:   "{{ .NodeName }}_{{ .Namespace }}",
+			want:   want{err: true},
+		},
+		"NotUnique": {
+			reason: "A template that produces the same name for different nodes should be rejected",
+			tmpl:   "ak-{{ .Namespace }}",
+			want:   want{err: true},
+		},
+		"UnknownField": {
+			reason: "A template that references an unknown field should be rejected",
+			tmpl:   "{{ .Cool }}",
+			want:   want{err: true},
+		},
+		"Unparseable": {
+			reason: "A template that cannot be parsed should be rejected",
+			tmpl:   "{{ .NodeName",
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			n, err := NewTemplateNamespaceNamer(tc.tmpl)
+			if diff := cmp.Diff(tc.want.err, err != nil); diff != "" {
+				t.Fatalf("\n%s\nNewTemplateNamespaceNamer(...): -want error, +got error: \n%s\n", tc.reason, diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want.name, n.NamespaceName(nodeName, nsName)); diff != "" {
+				t.Errorf("\n%s\nn.NamespaceName(...): -want, +got: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
 func TestIsTokenVolume(t *testing.T) {
 	cases := map[string]struct {
 		reason string
@@ -497,3 +1039,128 @@ func TestPrepareServiceAccountTokenSecret(t *testing.T) {
 		})
 	}
 }
+
+func TestPreparePersistentVolumeClaim(t *testing.T) {
+	local := "local"
+	mapped := "mapped"
+	unmapped := "unmapped"
+	classes := map[string]string{local: mapped}
+
+	cases := map[string]struct {
+		reason string
+		c      *corev1.PersistentVolumeClaim
+		want   *corev1.PersistentVolumeClaim
+	}{
+		"MappedStorageClass": {
+			reason: "Local binding details should be removed, and the storage class should be mapped",
+			c: &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"pv.kubernetes.io/bind-completed":    "yes",
+						"volume.kubernetes.io/selected-node": nodeName,
+						"cool":                               "true",
+					},
+					Finalizers: []string{"kubernetes.io/pvc-protection"},
+				},
+				Spec: corev1.PersistentVolumeClaimSpec{
+					VolumeName:       "coolvolume",
+					Selector:         &metav1.LabelSelector{},
+					StorageClassName: &local,
+				},
+				Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+			},
+			want: &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{"cool": "true"},
+				},
+				Spec: corev1.PersistentVolumeClaimSpec{
+					StorageClassName: &mapped,
+				},
+			},
+		},
+		"UnmappedStorageClass": {
+			reason: "Storage classes that are not mapped should be left as is",
+			c: &corev1.PersistentVolumeClaim{
+				Spec: corev1.PersistentVolumeClaimSpec{StorageClassName: &unmapped},
+			},
+			want: &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}},
+				Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: &unmapped},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			PreparePersistentVolumeClaim(tc.c, classes)
+			if diff := cmp.Diff(tc.want, tc.c); diff != "" {
+				t.Errorf("\n%s\nPreparePersistentVolumeClaim(...): -want, +got: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestExposingService(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: nodeName + nsNameHash,
+			Name:      name,
+			Labels: map[string]string{
+				LabelKeyNodeName:        nodeName,
+				LabelKeyNamespace:       nsName,
+				LabelKeyExposingService: "ak-pod-cool",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Ports: []corev1.ContainerPort{{ContainerPort: 8080}}},
+				{Ports: []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP}, {ContainerPort: 53, Protocol: corev1.ProtocolUDP}}},
+			},
+		},
+	}
+
+	type args struct {
+		pod *corev1.Pod
+		t   corev1.ServiceType
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   *corev1.Service
+	}{
+		"LoadBalancer": {
+			reason: "A service exposing each distinct container port at a matching port should be returned",
+			args: args{
+				pod: pod,
+				t:   corev1.ServiceTypeLoadBalancer,
+			},
+			want: &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: nodeName + nsNameHash,
+					Name:      "ak-pod-cool",
+					Labels: map[string]string{
+						LabelKeyNodeName:  nodeName,
+						LabelKeyNamespace: nsName,
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Type:     corev1.ServiceTypeLoadBalancer,
+					Selector: map[string]string{LabelKeyExposingService: "ak-pod-cool"},
+					Ports: []corev1.ServicePort{
+						{Name: "tcp-8080", Protocol: corev1.ProtocolTCP, Port: 8080, TargetPort: intstr.FromInt(8080)},
+						{Name: "udp-53", Protocol: corev1.ProtocolUDP, Port: 53, TargetPort: intstr.FromInt(53)},
+					},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ExposingService(tc.args.pod, tc.args.t)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nExposingService(...): -want, +got: \n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}